@@ -0,0 +1,134 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"golang-fast-start/07-goroutines-channels/ex-5/weather"
+)
+
+// Alert is what the notifiers get.
+type Alert struct {
+	State     string    `json:"state"` // "firing" or "resolved"
+	Rule      string    `json:"rule"`
+	City      string    `json:"city"`
+	Metric    string    `json:"metric"`
+	Value     float64   `json:"value"`
+	Threshold float64   `json:"threshold"`
+	Polls     int       `json:"polls"` // how many polls in a row matched
+	Time      time.Time `json:"time"`
+}
+
+const (
+	Firing   = "firing"
+	Resolved = "resolved"
+)
+
+func (a Alert) String() string {
+	return fmt.Sprintf("[%v] %v: %v (%v = %.2f, %d polls)", strings.ToUpper(a.State), a.City, a.Rule, a.Metric, a.Value, a.Polls)
+}
+
+// Engine keeps, for every rule and city, how many polls in a row matched, so a rule
+// fires once when its condition starts to hold and not on every poll while it lasts.
+// When the condition stops holding a "resolved" alert is sent.
+//
+// Evaluate may be called from the goroutines of a fan-out at the same time.
+type Engine struct {
+	Rules     []Rule
+	Notifiers []Notifier
+	Units     weather.Units // the units of the client the responses come from
+
+	// Repeat sends a firing alert again when the condition still holds this long after
+	// the last one, 0 sends it only once per episode.
+	Repeat time.Duration
+
+	mu    sync.Mutex
+	state map[string]*ruleState // rule index + city -> state
+}
+
+type ruleState struct {
+	streak   int // consecutive matching polls
+	firing   bool
+	lastSent time.Time
+}
+
+// NewEngine returns an engine that sends the alerts of rules to every notifier.
+func NewEngine(rules []Rule, units weather.Units, notifiers ...Notifier) *Engine {
+	return &Engine{Rules: rules, Units: units, Notifiers: notifiers}
+}
+
+// Evaluate checks every rule against one poll of city and notifies about the rules that
+// started or stopped firing. The errors of all notifiers are joined, a failing webhook
+// doesn't keep the alert from the other notifiers.
+func (e *Engine) Evaluate(ctx context.Context, city string, data weather.WeatherResponse) error {
+	alerts := e.check(city, data)
+
+	var errs []error
+	for _, a := range alerts {
+		for _, n := range e.Notifiers {
+			if err := n.Notify(ctx, a); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// check updates the state under the lock and returns the alerts to send, so the slow
+// part (a webhook) runs without holding it.
+func (e *Engine) check(city string, data weather.WeatherResponse) []Alert {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.state == nil {
+		e.state = make(map[string]*ruleState)
+	}
+	now := time.Now()
+
+	var alerts []Alert
+	for i, rule := range e.Rules {
+		if !rule.appliesTo(city, data) {
+			continue
+		}
+
+		key := fmt.Sprintf("%d|%v", i, strings.ToLower(city))
+		st, ok := e.state[key]
+		if !ok {
+			st = &ruleState{}
+			e.state[key] = st
+		}
+
+		value := rule.value(data, e.Units)
+		alert := Alert{
+			Rule: rule.Text, City: city, Metric: rule.Metric,
+			Value: weather.Round2(value), Threshold: rule.Threshold, Time: now,
+		}
+
+		if !rule.matches(value) {
+			if st.firing {
+				alert.State, alert.Polls = Resolved, st.streak
+				alerts = append(alerts, alert)
+			}
+			*st = ruleState{}
+			continue
+		}
+
+		st.streak++
+		alert.State, alert.Polls = Firing, st.streak
+		switch {
+		case st.streak < rule.Polls:
+			// not long enough yet
+		case !st.firing:
+			st.firing, st.lastSent = true, now
+			alerts = append(alerts, alert)
+		case e.Repeat > 0 && now.Sub(st.lastSent) >= e.Repeat:
+			st.lastSent = now
+			alerts = append(alerts, alert)
+		}
+	}
+	return alerts
+}
@@ -0,0 +1,196 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"golang-fast-start/07-goroutines-channels/ex-5/weather"
+)
+
+// recorder is a Notifier that keeps what it got.
+type recorder struct {
+	mu     sync.Mutex
+	alerts []Alert
+}
+
+func (r *recorder) Notify(_ context.Context, a Alert) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.alerts = append(r.alerts, a)
+	return nil
+}
+
+// take returns the alerts since the last call.
+func (r *recorder) take() []Alert {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	alerts := r.alerts
+	r.alerts = nil
+	return alerts
+}
+
+func celsius(temp float64) weather.WeatherResponse {
+	var data weather.WeatherResponse
+	data.Main.Temp = weather.Temperature{Value: temp, Unit: weather.Celsius}
+	return data
+}
+
+func mustParse(t *testing.T, texts ...string) []Rule {
+	t.Helper()
+	var rules []Rule
+	for _, text := range texts {
+		rule, err := ParseRule(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// poll evaluates one temperature of city and returns the states of the alerts it sent.
+func poll(t *testing.T, e *Engine, rec *recorder, city string, temp float64) []string {
+	t.Helper()
+	if err := e.Evaluate(context.Background(), city, celsius(temp)); err != nil {
+		t.Fatal(err)
+	}
+	var states []string
+	for _, a := range rec.take() {
+		states = append(states, a.State)
+	}
+	return states
+}
+
+func TestEngineFiresOnceAndResolves(t *testing.T) {
+	rec := &recorder{}
+	e := NewEngine(mustParse(t, "temp_c > 35 for 2 polls"), weather.Metric, rec)
+
+	steps := []struct {
+		temp float64
+		want string // "" for no alert
+	}{
+		{36, ""},       // one poll isn't enough
+		{30, ""},       // and the streak starts over
+		{36, ""},       //
+		{37, Firing},   // two in a row
+		{38, ""},       // still firing, not sent again
+		{30, Resolved}, //
+		{30, ""},       // nothing to resolve anymore
+		{36, ""},       // a new episode needs two polls again
+		{36, Firing},
+	}
+	for i, step := range steps {
+		got := poll(t, e, rec, "Ankara", step.temp)
+		if step.want == "" && len(got) != 0 || step.want != "" && (len(got) != 1 || got[0] != step.want) {
+			t.Errorf("poll %d (%v°C): alerts %v, want %q", i+1, step.temp, got, step.want)
+		}
+	}
+}
+
+func TestEngineKeepsCitiesApart(t *testing.T) {
+	rec := &recorder{}
+	e := NewEngine(mustParse(t, "temp_c > 35 for 2 polls", "oslo: temp_c < -15"), weather.Metric, rec)
+
+	// Paris and Ankara alternate, each has its own streak
+	poll(t, e, rec, "Paris", 36)
+	if got := poll(t, e, rec, "Ankara", 36); len(got) != 0 {
+		t.Errorf("Ankara fired on its first poll: %v", got)
+	}
+	if got := poll(t, e, rec, "Paris", 30); len(got) != 0 {
+		t.Errorf("Paris at 30°C: %v", got)
+	}
+	if err := e.Evaluate(context.Background(), "Ankara", celsius(36)); err != nil {
+		t.Fatal(err)
+	}
+	if got := rec.take(); len(got) != 1 || got[0].City != "Ankara" || got[0].Polls != 2 {
+		t.Errorf("got %v, want Ankara firing after 2 polls", got)
+	}
+
+	// the city prefix matches the requested city and ignores case
+	if got := poll(t, e, rec, "Paris", -20); len(got) != 0 {
+		t.Errorf("an Oslo rule fired for Paris: %v", got)
+	}
+	if got := poll(t, e, rec, "OSLO", -20); len(got) != 1 || got[0] != Firing {
+		t.Errorf("Oslo at -20°C: %v", got)
+	}
+}
+
+func TestEngineRepeat(t *testing.T) {
+	rec := &recorder{}
+	e := NewEngine(mustParse(t, "temp_c > 35"), weather.Metric, rec)
+	e.Repeat = 50 * time.Millisecond
+
+	if got := poll(t, e, rec, "Ankara", 36); len(got) != 1 {
+		t.Fatalf("first poll: %v", got)
+	}
+	if got := poll(t, e, rec, "Ankara", 36); len(got) != 0 {
+		t.Errorf("repeated before Repeat passed: %v", got)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if got := poll(t, e, rec, "Ankara", 36); len(got) != 1 || got[0] != Firing {
+		t.Errorf("not repeated after Repeat passed: %v", got)
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		received []Alert
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			http.Error(w, "want a JSON POST", http.StatusBadRequest)
+			return
+		}
+		var a Alert
+		if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		received = append(received, a)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	e := NewEngine(mustParse(t, "Ankara: temp_c >= 35"), weather.Metric, &WebhookNotifier{URL: srv.URL})
+	for _, temp := range []float64{36, 37, 38, 20, 20} {
+		if err := e.Evaluate(context.Background(), "Ankara", celsius(temp)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 2 {
+		t.Fatalf("webhook got %d alerts, want firing then resolved: %v", len(received), received)
+	}
+	first, second := received[0], received[1]
+	if first.State != Firing || first.City != "Ankara" || first.Value != 36 || first.Threshold != 35 || first.Rule != "Ankara: temp_c >= 35" {
+		t.Errorf("first alert %+v", first)
+	}
+	if second.State != Resolved || second.Value != 20 || second.Polls != 3 {
+		t.Errorf("second alert %+v, want resolved at 20 after 3 polls", second)
+	}
+}
+
+func TestWebhookErrorDoesntStopOtherNotifiers(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	rec := &recorder{}
+	e := NewEngine(mustParse(t, "temp_c > 35"), weather.Metric, &WebhookNotifier{URL: srv.URL}, rec)
+	if err := e.Evaluate(context.Background(), "Ankara", celsius(36)); err == nil {
+		t.Error("a 503 from the webhook was not reported")
+	}
+	if got := rec.take(); len(got) != 1 {
+		t.Errorf("the other notifier got %v", got)
+	}
+}
@@ -0,0 +1,115 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Notifier delivers an alert somewhere, the Engine doesn't care where.
+type Notifier interface {
+	Notify(ctx context.Context, a Alert) error
+}
+
+// ParseNotifier turns a command line spec into a notifier:
+//
+//	stdout                 one line per alert on w
+//	file:alerts.jsonl      appended to the file as JSON lines
+//	http://host/hook       POSTed as JSON (https too)
+func ParseNotifier(spec string, w io.Writer) (Notifier, error) {
+	switch {
+	case spec == "stdout":
+		return &WriterNotifier{W: w}, nil
+	case strings.HasPrefix(spec, "file:"):
+		path := strings.TrimPrefix(spec, "file:")
+		if path == "" {
+			return nil, fmt.Errorf("notifier %q: missing file name, e.g. file:alerts.jsonl", spec)
+		}
+		return &FileNotifier{Path: path}, nil
+	case strings.HasPrefix(spec, "http://"), strings.HasPrefix(spec, "https://"):
+		return &WebhookNotifier{URL: spec}, nil
+	}
+	return nil, fmt.Errorf("unknown notifier %q, use stdout, file:PATH or an http(s) URL", spec)
+}
+
+// WriterNotifier prints a line per alert.
+type WriterNotifier struct {
+	W io.Writer
+
+	mu sync.Mutex // lines of concurrent alerts don't interleave
+}
+
+func (n *WriterNotifier) Notify(_ context.Context, a Alert) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	_, err := fmt.Fprintln(n.W, a)
+	return err
+}
+
+// FileNotifier appends every alert to Path as one JSON line.
+type FileNotifier struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+func (n *FileNotifier) Notify(_ context.Context, a Alert) error {
+	line, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	f, err := os.OpenFile(n.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// WebhookNotifier POSTs every alert as JSON to URL. Anything but a 2xx answer is an error.
+// The fakeserver command accepts them on /webhook, so this can be tried without a real service.
+type WebhookNotifier struct {
+	URL        string
+	HTTPClient *http.Client // nil uses a client with a 10 second timeout
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, a Alert) error {
+	body, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := n.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body) // lets the connection be reused
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook %v: %v", n.URL, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+// Package alert evaluates small threshold rules against weather responses and sends an
+// alert when one matches. A rule reads like the condition it checks:
+//
+//	temp_c > 35
+//	wind > 20 for 2 polls
+//	Oslo: temp_c < -15
+//	humidity >= 90 for 3 polls
+//
+// "for N polls" only fires after the condition held N times in a row for the same city,
+// and an optional "CITY:" prefix limits the rule to that city. See Metrics for the names
+// that can be compared.
+package alert
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang-fast-start/07-goroutines-channels/ex-5/weather"
+)
+
+// metric reads one number out of a response. units is what the client asked for, only
+// needed where the API answers in different units depending on it.
+type metric func(r weather.WeatherResponse, units weather.Units) float64
+
+// Metrics maps the names usable in rules to what they read. Wind speeds are in m/s
+// whatever the units of the client, so a rule means the same with -units imperial.
+var Metrics = map[string]metric{
+	"temp_c":       func(r weather.WeatherResponse, _ weather.Units) float64 { return r.Main.Temp.Celsius() },
+	"temp_f":       func(r weather.WeatherResponse, _ weather.Units) float64 { return r.Main.Temp.Fahrenheit() },
+	"temp_k":       func(r weather.WeatherResponse, _ weather.Units) float64 { return r.Main.Temp.Kelvin() },
+	"feels_like_c": func(r weather.WeatherResponse, _ weather.Units) float64 { return r.Main.FeelsLike.Celsius() },
+	"humidity":     func(r weather.WeatherResponse, _ weather.Units) float64 { return float64(r.Main.Humidity) },
+	"pressure":     func(r weather.WeatherResponse, _ weather.Units) float64 { return float64(r.Main.Pressure) },
+	"wind":         func(r weather.WeatherResponse, u weather.Units) float64 { return metersPerSecond(r.Wind.Speed, u) },
+	"gust":         func(r weather.WeatherResponse, u weather.Units) float64 { return metersPerSecond(r.Wind.Gust, u) },
+	"clouds":       func(r weather.WeatherResponse, _ weather.Units) float64 { return float64(r.Clouds.All) },
+	"rain_1h":      func(r weather.WeatherResponse, _ weather.Units) float64 { return r.Rain.OneHour },
+	"snow_1h":      func(r weather.WeatherResponse, _ weather.Units) float64 { return r.Snow.OneHour },
+	"visibility":   func(r weather.WeatherResponse, _ weather.Units) float64 { return float64(r.Visibility) },
+}
+
+func metersPerSecond(speed float64, units weather.Units) float64 {
+	if units == weather.Imperial {
+		return speed * 0.44704 // mph
+	}
+	return speed
+}
+
+// operators, longest first so ">=" isn't read as ">" followed by "=35"
+var operators = []string{">=", "<=", "==", "!=", ">", "<"}
+
+// Rule is one parsed line of a rules file.
+type Rule struct {
+	Text      string // as written, used in alert messages
+	City      string // "" matches every city
+	Metric    string
+	Op        string
+	Threshold float64
+	Polls     int // consecutive matching polls needed before it fires, at least 1
+}
+
+// ParseRule parses "[CITY:] METRIC OP NUMBER [for N polls]".
+func ParseRule(text string) (Rule, error) {
+	text = strings.TrimSpace(text)
+	rule := Rule{Text: text, Polls: 1}
+	expr := text
+
+	// "for N polls" (or "for 1 poll") at the end
+	if before, after, ok := strings.Cut(expr, " for "); ok {
+		fields := strings.Fields(after)
+		if len(fields) != 2 || (fields[1] != "polls" && fields[1] != "poll") {
+			return Rule{}, fmt.Errorf("rule %q: expected \"for N polls\" at the end", text)
+		}
+		n, err := strconv.Atoi(fields[0])
+		if err != nil || n < 1 {
+			return Rule{}, fmt.Errorf("rule %q: %q is not a positive number of polls", text, fields[0])
+		}
+		rule.Polls = n
+		expr = before
+	}
+
+	if city, rest, ok := strings.Cut(expr, ":"); ok {
+		rule.City = strings.TrimSpace(city)
+		expr = rest
+	}
+
+	for _, op := range operators {
+		left, right, ok := strings.Cut(expr, op)
+		if !ok {
+			continue
+		}
+		rule.Metric = strings.TrimSpace(left)
+		rule.Op = op
+		threshold, err := strconv.ParseFloat(strings.TrimSpace(right), 64)
+		if err != nil {
+			return Rule{}, fmt.Errorf("rule %q: %q is not a number", text, strings.TrimSpace(right))
+		}
+		rule.Threshold = threshold
+		break
+	}
+	if rule.Op == "" {
+		return Rule{}, fmt.Errorf("rule %q: expected METRIC OP NUMBER, e.g. temp_c > 35", text)
+	}
+	if _, ok := Metrics[rule.Metric]; !ok {
+		names := slices.Sorted(maps.Keys(Metrics))
+		return Rule{}, fmt.Errorf("rule %q: unknown metric %q, use one of %v", text, rule.Metric, strings.Join(names, ", "))
+	}
+	return rule, nil
+}
+
+// ParseRules reads one rule per line, blank lines and # comments are skipped.
+func ParseRules(r io.Reader) ([]Rule, error) {
+	var rules []Rule
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		rule, err := ParseRule(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, scanner.Err()
+}
+
+// appliesTo reports whether the rule is about city, by the requested or the resolved name.
+func (r Rule) appliesTo(city string, data weather.WeatherResponse) bool {
+	return r.City == "" || strings.EqualFold(r.City, city) || strings.EqualFold(r.City, data.Name)
+}
+
+// value reads the rule's metric out of data.
+func (r Rule) value(data weather.WeatherResponse, units weather.Units) float64 {
+	return Metrics[r.Metric](data, units)
+}
+
+// matches compares value with the threshold.
+func (r Rule) matches(value float64) bool {
+	switch r.Op {
+	case ">":
+		return value > r.Threshold
+	case ">=":
+		return value >= r.Threshold
+	case "<":
+		return value < r.Threshold
+	case "<=":
+		return value <= r.Threshold
+	case "==":
+		return value == r.Threshold
+	case "!=":
+		return value != r.Threshold
+	}
+	return false
+}
+
+func (r Rule) String() string { return r.Text }
@@ -0,0 +1,71 @@
+package alert
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		text string
+		want Rule
+	}{
+		{"temp_c > 35", Rule{Metric: "temp_c", Op: ">", Threshold: 35, Polls: 1}},
+		// the two-character operators aren't read as ">" or "<" followed by "=35"
+		{"temp_c >= 35", Rule{Metric: "temp_c", Op: ">=", Threshold: 35, Polls: 1}},
+		{"humidity<=20", Rule{Metric: "humidity", Op: "<=", Threshold: 20, Polls: 1}},
+		{"clouds != 0", Rule{Metric: "clouds", Op: "!=", Threshold: 0, Polls: 1}},
+		{"Oslo: temp_c < -15", Rule{City: "Oslo", Metric: "temp_c", Op: "<", Threshold: -15, Polls: 1}},
+		{"New York: wind > 20 for 2 polls", Rule{City: "New York", Metric: "wind", Op: ">", Threshold: 20, Polls: 2}},
+		{"  gust == 1.5 for 1 poll ", Rule{Metric: "gust", Op: "==", Threshold: 1.5, Polls: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, err := ParseRule(tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.want.Text = strings.TrimSpace(tt.text)
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRuleErrors(t *testing.T) {
+	tests := []struct {
+		text string
+		want string // part of the message
+	}{
+		{"temp_c 35", "expected METRIC OP NUMBER"},
+		{"temp_c > hot", `"hot" is not a number`},
+		{"temperature > 35", `unknown metric "temperature"`},
+		{"temp_c > 35 for 0 polls", "not a positive number of polls"},
+		{"temp_c > 35 for two polls", "not a positive number of polls"},
+		{"temp_c > 35 for 2 minutes", `expected "for N polls"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			_, err := ParseRule(tt.text)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got %v, want an error with %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules(strings.NewReader("# heat\ntemp_c > 35\n\nOslo: temp_c < -15\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 2 || rules[1].City != "Oslo" {
+		t.Errorf("got %+v", rules)
+	}
+
+	_, err = ParseRules(strings.NewReader("temp_c > 35\n\nwind >\n"))
+	if err == nil || !strings.HasPrefix(err.Error(), "line 3:") {
+		t.Errorf("got %v, want the error on line 3", err)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"golang-fast-start/07-goroutines-channels/ex-5/alert"
+	"golang-fast-start/07-goroutines-channels/ex-5/weather"
+)
+
+// alertFlags are the alerting flags shared by current and daemon.
+type alertFlags struct {
+	rules  *string
+	notify *stringList
+	repeat *time.Duration
+}
+
+// stringList is a repeatable string flag.
+type stringList []string
+
+func (l *stringList) String() string     { return strings.Join(*l, ",") }
+func (l *stringList) Set(v string) error { *l = append(*l, v); return nil }
+
+func registerAlertFlags(fs *flag.FlagSet) *alertFlags {
+	af := &alertFlags{notify: &stringList{}}
+	af.rules = fs.String("rules", "", `alert rules file, one rule per line like "wind > 20 for 2 polls"`)
+	fs.Var(af.notify, "notify", "where alerts go: stdout, file:PATH or an http(s) webhook URL (repeatable, default stdout, which is stderr when stdout carries JSON or CSV)")
+	af.repeat = fs.Duration("repeat", 0, "send a firing alert again after this long, 0 sends it once until it resolves")
+	return af
+}
+
+// engine loads the rules, nil when there is no -rules file. The "stdout" notifier
+// writes to out, which the command points at stderr when its stdout is machine-readable.
+func (af *alertFlags) engine(units weather.Units, out io.Writer) (*alert.Engine, error) {
+	if *af.rules == "" {
+		if len(*af.notify) > 0 {
+			return nil, fmt.Errorf("-notify needs a -rules file")
+		}
+		return nil, nil
+	}
+
+	f, err := os.Open(*af.rules)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	rules, err := alert.ParseRules(f)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", *af.rules, err)
+	}
+
+	specs := *af.notify
+	if len(specs) == 0 {
+		specs = []string{"stdout"}
+	}
+	var notifiers []alert.Notifier
+	for _, spec := range specs {
+		n, err := alert.ParseNotifier(spec, out)
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, n)
+	}
+
+	engine := alert.NewEngine(rules, units, notifiers...)
+	engine.Repeat = *af.repeat
+	return engine, nil
+}
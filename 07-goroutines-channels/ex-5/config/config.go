@@ -0,0 +1,384 @@
+// Package config merges the settings of the weather command from five sources.
+// Later sources win:
+//
+//  1. built-in defaults
+//  2. a JSON config file (-config or WEATHER_CONFIG)
+//  3. the .env file
+//  4. the real environment
+//  5. command line flags that were given explicitly
+//
+// The API key can come from every source but the command line, where it would show up
+// in the shell history and the process list. It is never printed in plain text.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"maps"
+	"net"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+
+	"golang-fast-start/07-goroutines-channels/ex-5/weather"
+)
+
+// Config is the merged result. Use Load to get one.
+type Config struct {
+	APIKey   string
+	BaseURL  string
+	Units    weather.Units
+	CacheDir string
+	CacheTTL time.Duration
+	Rate     int // API calls per minute, 0 disables the limiter
+	Burst    int
+	Geocode  bool
+	Debug    bool   // log every request to stderr, the API key redacted
+	Metrics  string // listen address of the /metrics endpoint, "" turns it off
+	DataDir  string // observation store of the daemon and history commands
+
+	Providers []string // asked in order until one answers, see ProviderNames
+	WttrURL   string
+
+	sources map[string]string // setting name -> where its value came from
+}
+
+// ProviderNames are the weather providers the providers setting accepts.
+var ProviderNames = []string{"openweathermap", "wttr", "fake"}
+
+// setting describes one configurable value and its name in every source.
+type setting struct {
+	name    string // flag name, the JSON key is the same with "_" instead of "-"
+	env     string // environment / .env variable, "" if it can't be set there
+	flag    bool   // whether there is a command line flag for it
+	boolean bool   // a switch: -geocode instead of -geocode=true
+	def     string
+	usage   string
+	set     func(c *Config, value string) error
+	display func(c *Config) string
+}
+
+var settings = []setting{
+	{
+		name: "api-key", env: "OPENWEATHER_API_KEY",
+		usage:   "OpenWeatherMap API key",
+		set:     func(c *Config, v string) error { c.APIKey = v; return nil },
+		display: func(c *Config) string { return Redact(c.APIKey) },
+	},
+	{
+		name: "base-url", env: "OPENWEATHER_BASE_URL", flag: true, def: weather.DefaultBaseURL,
+		usage: "API root, point it at the fakeserver to work offline",
+		set: func(c *Config, v string) error {
+			u, err := url.Parse(v)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("%q is not an http(s) URL, e.g. %v", v, weather.DefaultBaseURL)
+			}
+			c.BaseURL = strings.TrimSuffix(v, "/")
+			return nil
+		},
+		display: func(c *Config) string { return c.BaseURL },
+	},
+	{
+		name: "units", env: "WEATHER_UNITS", flag: true, def: string(weather.Metric),
+		usage: "unit system: standard, metric or imperial",
+		set: func(c *Config, v string) (err error) {
+			c.Units, err = weather.ParseUnits(v)
+			return err
+		},
+		display: func(c *Config) string { return string(c.Units) },
+	},
+	{
+		name: "cache-dir", env: "WEATHER_CACHE_DIR", flag: true,
+		usage:   "directory of the on-disk cache, empty disables it",
+		set:     func(c *Config, v string) error { c.CacheDir = v; return nil },
+		display: func(c *Config) string { return c.CacheDir },
+	},
+	{
+		name: "cache-ttl", env: "WEATHER_CACHE_TTL", flag: true, def: "10m",
+		usage: "how long cached responses are reused",
+		set: func(c *Config, v string) (err error) {
+			c.CacheTTL, err = parseDuration(v)
+			return err
+		},
+		display: func(c *Config) string { return c.CacheTTL.String() },
+	},
+	{
+		name: "rate", env: "WEATHER_RATE", flag: true, def: "60",
+		usage: "maximum API calls per minute, 0 disables the limit",
+		set: func(c *Config, v string) (err error) {
+			c.Rate, err = parseCount(v, 0)
+			return err
+		},
+		display: func(c *Config) string { return strconv.Itoa(c.Rate) },
+	},
+	{
+		name: "burst", env: "WEATHER_BURST", flag: true, def: "10",
+		usage: "calls allowed at once before -rate kicks in",
+		set: func(c *Config, v string) (err error) {
+			c.Burst, err = parseCount(v, 1)
+			return err
+		},
+		display: func(c *Config) string { return strconv.Itoa(c.Burst) },
+	},
+	{
+		name: "data-dir", env: "WEATHER_DATA_DIR", flag: true, def: ".weather-data",
+		usage:   "directory where the daemon stores observations",
+		set:     func(c *Config, v string) error { c.DataDir = v; return nil },
+		display: func(c *Config) string { return c.DataDir },
+	},
+	{
+		name: "providers", env: "WEATHER_PROVIDERS", flag: true, def: "openweathermap",
+		usage: "comma separated providers tried in order: " + strings.Join(ProviderNames, ", "),
+		set: func(c *Config, v string) error {
+			c.Providers = nil
+			for _, name := range strings.Split(v, ",") {
+				name = strings.TrimSpace(name)
+				if !slices.Contains(ProviderNames, name) {
+					return fmt.Errorf("unknown provider %q, use %v", name, strings.Join(ProviderNames, ", "))
+				}
+				c.Providers = append(c.Providers, name)
+			}
+			return nil
+		},
+		display: func(c *Config) string { return strings.Join(c.Providers, ",") },
+	},
+	{
+		name: "wttr-url", env: "WTTR_BASE_URL", flag: true, def: weather.DefaultWttrURL,
+		usage: "wttr.in root, point it at the fakeserver to work offline",
+		set: func(c *Config, v string) error {
+			u, err := url.Parse(v)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("%q is not an http(s) URL, e.g. %v", v, weather.DefaultWttrURL)
+			}
+			c.WttrURL = strings.TrimSuffix(v, "/")
+			return nil
+		},
+		display: func(c *Config) string { return c.WttrURL },
+	},
+	{
+		name: "geocode", env: "WEATHER_GEOCODE", flag: true, boolean: true, def: "false",
+		usage: `resolve city names like "Springfield,US" to coordinates first`,
+		set: func(c *Config, v string) (err error) {
+			c.Geocode, err = strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("%q is not a boolean, use true or false", v)
+			}
+			return nil
+		},
+		display: func(c *Config) string { return strconv.FormatBool(c.Geocode) },
+	},
+	{
+		name: "debug", env: "WEATHER_DEBUG", flag: true, boolean: true, def: "false",
+		usage: "log every API request to stderr",
+		set: func(c *Config, v string) (err error) {
+			c.Debug, err = strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("%q is not a boolean, use true or false", v)
+			}
+			return nil
+		},
+		display: func(c *Config) string { return strconv.FormatBool(c.Debug) },
+	},
+	{
+		name: "metrics-addr", env: "WEATHER_METRICS_ADDR", flag: true,
+		usage: "serve Prometheus metrics on this address, e.g. localhost:9090",
+		set: func(c *Config, v string) error {
+			if v != "" {
+				if _, _, err := net.SplitHostPort(v); err != nil {
+					return fmt.Errorf("%q is not a host:port address, e.g. localhost:9090", v)
+				}
+			}
+			c.Metrics = v
+			return nil
+		},
+		display: func(c *Config) string { return c.Metrics },
+	},
+}
+
+// Flags holds the flags Register added to a FlagSet until Load reads them.
+type Flags struct {
+	fs         *flag.FlagSet
+	configFile *string
+	envFile    *string
+
+	// APIKeyOptional skips the API key check, for commands that never call the API.
+	APIKeyOptional bool
+
+	// LookupEnv reads the real environment, os.LookupEnv unless replaced.
+	LookupEnv func(key string) (string, bool)
+}
+
+// Register adds -config, -env-file and a flag per setting to fs.
+func Register(fs *flag.FlagSet) *Flags {
+	f := &Flags{fs: fs, LookupEnv: os.LookupEnv}
+	f.configFile = fs.String("config", os.Getenv("WEATHER_CONFIG"), "JSON config file (env WEATHER_CONFIG)")
+	f.envFile = fs.String("env-file", ".env", "dotenv file to read, a missing file is ignored")
+	for _, s := range settings {
+		if s.flag {
+			fs.Var(&flagValue{value: s.def, boolean: s.boolean}, s.name, fmt.Sprintf("%v (env %v)", s.usage, s.env))
+		}
+	}
+	return f
+}
+
+// Load merges all sources, call it after fs.Parse. Every problem is reported together,
+// each with the source it came from, so a broken setup is fixed in one go instead of
+// one error per run.
+func (f *Flags) Load() (*Config, error) {
+	c := &Config{sources: make(map[string]string)}
+	var errs []error
+	for _, s := range settings {
+		errs = append(errs, c.apply(s, s.def, "default"))
+	}
+
+	if *f.configFile != "" {
+		errs = append(errs, c.loadFile(*f.configFile))
+	}
+
+	dotenv, err := godotenv.Read(*f.envFile)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		errs = append(errs, fmt.Errorf("reading %v: %w", *f.envFile, err))
+	}
+	for _, s := range settings {
+		if v, ok := dotenv[s.env]; ok && s.env != "" {
+			errs = append(errs, c.apply(s, v, *f.envFile+" "+s.env))
+		}
+	}
+
+	for _, s := range settings {
+		if v, ok := f.LookupEnv(s.env); ok && s.env != "" {
+			errs = append(errs, c.apply(s, v, "environment "+s.env))
+		}
+	}
+
+	// only flags given on the command line, the flag defaults are already in place
+	f.fs.Visit(func(fl *flag.Flag) {
+		if i := slices.IndexFunc(settings, func(s setting) bool { return s.flag && s.name == fl.Name }); i >= 0 {
+			errs = append(errs, c.apply(settings[i], fl.Value.String(), "flag -"+fl.Name))
+		}
+	})
+
+	// wttr.in and the fake provider work without a key
+	if c.APIKey == "" && !f.APIKeyOptional && slices.Contains(c.Providers, "openweathermap") {
+		errs = append(errs, errors.New("missing API key: set OPENWEATHER_API_KEY in the environment or in .env, "+
+			`or "api_key" in the -config file (get a key at https://openweathermap.org/api)`))
+	}
+
+	// nil errors are dropped, Join returns nil when there are no others
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// flagValue keeps the raw text of a flag, Load parses it like any other source.
+type flagValue struct {
+	value   string
+	boolean bool
+}
+
+func (v *flagValue) String() string     { return v.value }
+func (v *flagValue) Set(s string) error { v.value = s; return nil }
+func (v *flagValue) IsBoolFlag() bool   { return v.boolean }
+
+func (c *Config) apply(s setting, value, source string) error {
+	if err := s.set(c, strings.TrimSpace(value)); err != nil {
+		return fmt.Errorf("invalid %v from %v: %w", s.name, source, err)
+	}
+	c.sources[s.name] = source
+	return nil
+}
+
+// loadFile reads a flat JSON object, e.g. {"units": "imperial", "rate": 30, "cache_ttl": "5m"}.
+func (c *Config) loadFile(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config: %w", err)
+	}
+
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(b, &values); err != nil {
+		return fmt.Errorf("%v is not a JSON object: %w", path, err)
+	}
+
+	var errs []error
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		raw := values[key]
+		i := slices.IndexFunc(settings, func(s setting) bool { return strings.ReplaceAll(s.name, "-", "_") == key })
+		if i < 0 {
+			errs = append(errs, fmt.Errorf("unknown key %q in %v, known keys: %v", key, path, strings.Join(jsonKeys(), ", ")))
+			continue
+		}
+
+		// strings are unquoted, numbers and booleans are used as written
+		value := string(bytes.TrimSpace(raw))
+		var s string
+		if json.Unmarshal(raw, &s) == nil {
+			value = s
+		}
+		errs = append(errs, c.apply(settings[i], value, path))
+	}
+	return errors.Join(errs...)
+}
+
+func jsonKeys() []string {
+	keys := make([]string, len(settings))
+	for i, s := range settings {
+		keys[i] = strings.ReplaceAll(s.name, "-", "_")
+	}
+	return keys
+}
+
+// Describe lists every setting with its value and source, the API key redacted.
+func (c *Config) Describe() []string {
+	width := 0
+	for _, s := range settings {
+		width = max(width, len(s.name))
+	}
+	lines := make([]string, len(settings))
+	for i, s := range settings {
+		lines[i] = fmt.Sprintf("%-*v %-45v (%v)", width, s.name, s.display(c), c.sources[s.name])
+	}
+	return lines
+}
+
+// String is safe to log, it goes through Describe.
+func (c *Config) String() string {
+	return strings.Join(c.Describe(), "\n")
+}
+
+// Redact hides a secret but keeps enough to tell two keys apart: "abcd…(32 chars)".
+func Redact(secret string) string {
+	if secret == "" {
+		return "(not set)"
+	}
+	if len(secret) < 12 {
+		return fmt.Sprintf("****(%d chars)", len(secret))
+	}
+	return fmt.Sprintf("%v…(%d chars)", secret[:4], len(secret))
+}
+
+func parseDuration(v string) (time.Duration, error) {
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("%q is not a duration, use e.g. 30s, 10m or 1h", v)
+	}
+	return d, nil
+}
+
+func parseCount(v string, least int) (int, error) {
+	n, err := strconv.Atoi(v)
+	if err != nil || n < least {
+		return 0, fmt.Errorf("%q is not a whole number of at least %d", v, least)
+	}
+	return n, nil
+}
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDescribeAlignsLongNames(t *testing.T) {
+	c := Config{
+		APIKey: "0123456789abcdef", BaseURL: "http://localhost", Units: "metric",
+		CacheDir: "/tmp/cache", CacheTTL: time.Minute, Rate: 60, Burst: 10,
+		Metrics: "localhost:9090", DataDir: "/tmp/data",
+		Providers: []string{"openweathermap"}, WttrURL: "http://localhost",
+	}
+
+	lines := c.Describe()
+	if len(lines) != len(settings) {
+		t.Fatalf("%d lines for %d settings", len(lines), len(settings))
+	}
+	column := -1
+	for i, line := range lines {
+		name := settings[i].name
+		if !strings.HasPrefix(line, name+" ") {
+			t.Fatalf("line %q doesn't start with %v", line, name)
+		}
+		start := len(line) - len(strings.TrimLeft(line[len(name):], " "))
+		if column == -1 {
+			column = start
+		} else if start != column {
+			t.Errorf("value of %v starts at column %d, want %d:\n%v", name, start, column, strings.Join(lines, "\n"))
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"golang-fast-start/07-goroutines-channels/ex-5/config"
+	"golang-fast-start/07-goroutines-channels/ex-5/weather"
+)
+
+// runCurrent fetches the current weather of every city concurrently and renders one row per city.
+func runCurrent(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("current", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: weather current [flags] [city ...]")
+		fs.PrintDefaults()
+	}
+
+	cf := config.Register(fs)
+	file := fs.String("file", "", `read cities from a file, one per line ("-" for stdin)`)
+	concurrency := fs.Int("concurrency", 4, "number of concurrent requests, 0 starts one goroutine per city")
+	timeout := fs.Duration("timeout", 10*time.Second, "deadline for the whole run")
+	cityTimeout := fs.Duration("city-timeout", 5*time.Second, "deadline for a single city")
+	format := fs.String("format", "text", "output format: "+strings.Join(weather.Formats, ", "))
+	order := fs.String("order", "arrival", "result order: arrival (as they complete), input, name or temp")
+	failFast := fs.Bool("fail-fast", false, "cancel the remaining cities as soon as one fails")
+	alerting := registerAlertFlags(fs)
+
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	resultOrder, err := weather.ParseOrder(*order)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error:", err)
+		return exitUsage
+	}
+
+	cities, err := readCities(fs.Args(), *file, stdin)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error:", err)
+		return exitUsage
+	}
+
+	client, provider, err := loadProvider(cf)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error:", err)
+		return exitUsage
+	}
+
+	renderer, err := weather.NewRenderer(*format, stdout, client.Units.TempUnit())
+	if err != nil {
+		fmt.Fprintln(stderr, "Error:", err)
+		return exitUsage
+	}
+
+	// alert lines would break the json and csv output, they go next to the summary
+	alertOut := stdout
+	if *format != "text" {
+		alertOut = stderr
+	}
+	alerts, err := alerting.engine(client.Units, alertOut)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error:", err)
+		return exitUsage
+	}
+
+	// When the deadline passes the in-flight requests are cancelled instead of hanging forever.
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	// With -concurrency N at most N requests run at the same time, the rest wait in the job queue.
+	opts := weather.FanOutOptions{CityTimeout: *cityTimeout, Workers: *concurrency, Order: resultOrder, FailFast: *failFast}
+
+	// FetchEach calls us for every result as it arrives and returns the accounting at the end
+	summary, err := weather.FetchEach(ctx, provider, cities, opts, func(result weather.WeatherResult) error {
+		if err := renderer.Render(result); err != nil {
+			return err
+		}
+		if errors.Is(result.Err, weather.ErrUnauthorized) {
+			// no point in looking at the other cities, they will fail the same way
+			return errUnauthorized{result.Err}
+		}
+		if result.Err == nil && alerts != nil {
+			if err := alerts.Evaluate(ctx, result.City, result.Data); err != nil {
+				fmt.Fprintln(stderr, "Alert:", err)
+			}
+		}
+		return nil
+	})
+
+	closeErr := renderer.Close(summary.Elapsed)
+	var authErr errUnauthorized
+	switch {
+	case errors.As(err, &authErr):
+		fmt.Fprintln(stderr, "Error: check OPENWEATHER_API_KEY,", authErr.err)
+		return exitFailed
+	case err != nil:
+		fmt.Fprintln(stderr, "Error:", err)
+		return exitFailed
+	case closeErr != nil:
+		fmt.Fprintln(stderr, "Error:", closeErr)
+		return exitFailed
+	}
+
+	// the summary goes to stderr so it never ends up in the json/csv output
+	fmt.Fprintln(stderr, summary)
+
+	stats := client.Cache.Stats()
+	fmt.Fprintf(stderr, "Cache: %d hits, %d shared, %d misses (API calls)\n", stats.Hits, stats.Shared, stats.Misses)
+
+	if summary.Failures() > 0 {
+		return exitFailed
+	}
+	return exitOK
+}
+
+// errUnauthorized stops the run early, every other city would get the same 401.
+type errUnauthorized struct{ err error }
+
+func (e errUnauthorized) Error() string { return e.err.Error() }
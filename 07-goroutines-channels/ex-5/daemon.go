@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"golang-fast-start/07-goroutines-channels/ex-5/alert"
+	"golang-fast-start/07-goroutines-channels/ex-5/config"
+	"golang-fast-start/07-goroutines-channels/ex-5/store"
+	"golang-fast-start/07-goroutines-channels/ex-5/weather"
+)
+
+// runDaemon polls the cities every -interval until it gets SIGINT or SIGTERM.
+//
+// ex-3's doWork is stopped by main returning, whatever it was in the middle of. Here a
+// signal only stops new polls: the poll that is running gets -grace to finish, and only
+// then are its remaining requests cancelled. Either way every goroutine has returned
+// and every observation is written before the program exits.
+// SIGHUP re-reads -file, the next poll uses the new list.
+//
+// Observations are appended to the store in -data-dir, see "weather history".
+func runDaemon(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("daemon", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: weather daemon [flags] [city ...]")
+		fs.PrintDefaults()
+	}
+
+	cf := config.Register(fs)
+	file := fs.String("file", "", "read cities from a file, one per line, re-read on SIGHUP")
+	interval := fs.Duration("interval", 5*time.Minute, "time between two polls")
+	concurrency := fs.Int("concurrency", 4, "number of concurrent requests, 0 starts one goroutine per city")
+	cityTimeout := fs.Duration("city-timeout", 5*time.Second, "deadline for a single city")
+	grace := fs.Duration("grace", 10*time.Second, "how long a running poll may finish after SIGINT/SIGTERM")
+	out := fs.String("out", "", `also append observations as JSON lines to this file ("-" for stdout)`)
+	alerting := registerAlertFlags(fs)
+
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if *interval <= 0 {
+		fmt.Fprintln(stderr, "Error: -interval must be positive")
+		return exitUsage
+	}
+	cityArgs := fs.Args()
+	if *file == "-" || len(cityArgs) == 1 && cityArgs[0] == "-" {
+		fmt.Fprintln(stderr, "Error: the daemon can't re-read stdin on SIGHUP, use a file")
+		return exitUsage
+	}
+	cities, err := readCities(cityArgs, *file, nil)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error:", err)
+		return exitUsage
+	}
+
+	cfg, err := cf.Load()
+	if err != nil {
+		fmt.Fprintln(stderr, "Error:", err)
+		return exitUsage
+	}
+	client, err := newClient(cfg)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error:", err)
+		return exitUsage
+	}
+	provider := newProvider(cfg, client)
+	db, err := store.Open(cfg.DataDir)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error:", err)
+		return exitUsage
+	}
+	defer db.Close()
+	// one engine for the whole run, "for N polls" counts across polls
+	// with -out - stdout is JSON lines, the alerts go to the log instead
+	alertOut := stdout
+	if *out == "-" {
+		alertOut = stderr
+	}
+	alerts, err := alerting.engine(client.Units, alertOut)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error:", err)
+		return exitUsage
+	}
+	// Every poll should ask the API again, a cached response would be recorded twice.
+	// With a zero TTL the cache still merges duplicate cities within one poll. The disk
+	// cache is left out altogether: even revalidated, a 304 returns the stored response
+	// again, and the store already keeps every observation.
+	client.Cache.TTL = 0
+	client.DiskCache = nil
+
+	var enc *json.Encoder
+	switch *out {
+	case "":
+	case "-":
+		enc = json.NewEncoder(stdout)
+	default:
+		f, err := os.OpenFile(*out, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			fmt.Fprintln(stderr, "Error:", err)
+			return exitUsage
+		}
+		defer f.Close()
+		enc = json.NewEncoder(f)
+	}
+
+	logger := log.New(stderr, "daemon: ", log.LstdFlags)
+
+	// Buffered so a signal that arrives while we're busy isn't dropped.
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	// Cancelling ctx aborts the requests of the running poll, it's only done when -grace runs out.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	opts := weather.FanOutOptions{CityTimeout: *cityTimeout, Workers: *concurrency}
+	unit := client.Units.TempUnit()
+
+	// pollDone is nil while no poll runs, a receive from a nil channel blocks forever
+	// so the select below simply ignores it.
+	var pollDone chan struct{}
+	startPoll := func() {
+		pollDone = make(chan struct{})
+		go func(cities []string, done chan<- struct{}) {
+			defer close(done)
+			poll(ctx, provider, cities, opts, db, enc, alerts, unit, logger)
+		}(cities, pollDone)
+	}
+
+	logger.Printf("polling %d cities every %v into %v, pid %d", len(cities), *interval, cfg.DataDir, os.Getpid())
+	startPoll()
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if pollDone != nil {
+				// a slow poll never overlaps the next one, the tick is skipped instead
+				logger.Print("previous poll still running, skipping this one")
+				continue
+			}
+			startPoll()
+
+		case <-pollDone:
+			pollDone = nil
+
+		case sig := <-signals:
+			if sig == syscall.SIGHUP {
+				cities = reloadCities(cityArgs, *file, cities, logger)
+				continue
+			}
+			logger.Printf("%v received, shutting down", sig)
+			return drain(pollDone, *grace, cancel, signals, logger)
+		}
+	}
+}
+
+// poll fetches every city once, stores one observation per result and checks the alert
+// rules. enc and alerts may be nil.
+func poll(ctx context.Context, provider weather.Provider, cities []string, opts weather.FanOutOptions, db *store.Store, enc *json.Encoder, alerts *alert.Engine, unit weather.TempUnit, logger *log.Logger) {
+	start := time.Now()
+	var failed int
+
+	for result := range weather.FetchAllFrom(ctx, provider, cities, opts) {
+		obs := store.NewObservation(result, unit, start)
+		if result.Err != nil {
+			failed++
+		}
+		if err := db.Append(obs); err != nil {
+			logger.Print("storing observation: ", err)
+		}
+		if enc != nil {
+			if err := enc.Encode(obs); err != nil {
+				logger.Print("writing observation: ", err)
+			}
+		}
+		if alerts != nil && result.Err == nil {
+			if err := alerts.Evaluate(ctx, result.City, result.Data); err != nil {
+				logger.Print("alert: ", err)
+			}
+		}
+	}
+	logger.Printf("polled %d cities in %v, %d failed", len(cities), time.Since(start).Round(time.Millisecond), failed)
+}
+
+// reloadCities re-reads the city list for SIGHUP and keeps the old one if that fails.
+func reloadCities(args []string, file string, old []string, logger *log.Logger) []string {
+	if file == "" {
+		logger.Print("SIGHUP received but there is no -file to reload")
+		return old
+	}
+	cities, err := readCities(args, file, nil)
+	if err != nil {
+		logger.Printf("SIGHUP: keeping %d cities: %v", len(old), err)
+		return old
+	}
+	logger.Printf("SIGHUP: reloaded %v, %d cities (was %d)", file, len(cities), len(old))
+	return cities
+}
+
+// drain waits for the running poll, if any. When grace runs out or a second signal
+// arrives, the poll's context is cancelled and we wait for its goroutines to return.
+func drain(pollDone <-chan struct{}, grace time.Duration, cancel context.CancelFunc, signals <-chan os.Signal, logger *log.Logger) int {
+	if pollDone == nil {
+		return exitOK
+	}
+
+	logger.Printf("waiting up to %v for the running poll", grace)
+	timeout := time.After(grace)
+wait:
+	for {
+		select {
+		case <-pollDone:
+			return exitOK
+		case <-timeout:
+			logger.Print("grace period over, cancelling the remaining requests")
+			break wait
+		case sig := <-signals:
+			if sig == syscall.SIGHUP {
+				continue // too late to reload
+			}
+			logger.Printf("%v received again, cancelling the remaining requests", sig)
+			break wait
+		}
+	}
+
+	// cancel doesn't kill anything: every fetch sees ctx.Done, returns a result and the
+	// fan-out closes its channel, so this wait is short
+	cancel()
+	<-pollDone
+	return exitFailed
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDaemonRejectsStdin(t *testing.T) {
+	for _, args := range [][]string{{"-file", "-"}, {"-"}} {
+		var stderr bytes.Buffer
+		if code := runDaemon(args, &stderr, &stderr); code != exitUsage {
+			t.Errorf("daemon %v exited with %d, want %d", args, code, exitUsage)
+		}
+		if !strings.Contains(stderr.String(), "can't re-read stdin") {
+			t.Errorf("daemon %v printed %q", args, stderr.String())
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang-fast-start/07-goroutines-channels/ex-5/weather/fakeapi"
+)
+
+// A local OpenWeatherMap stand-in for offline development:
+//
+//	go run ./07-goroutines-channels/ex-5/fakeserver -addr :8081 -fault Paris=503/2 -fault Oslo=slow:3s
+//	go run ./07-goroutines-channels/ex-5 -base-url http://localhost:8081 London Paris Oslo
+
+// faultFlags collects repeated -fault flags.
+type faultFlags map[string]fakeapi.Fault
+
+func (f faultFlags) String() string { return fmt.Sprint(map[string]fakeapi.Fault(f)) }
+
+// Set parses CITY=SPEC where SPEC is one of
+//
+//	503        always answer 503
+//	503/2      answer 503 to the first 2 requests, then succeed
+//	slow:3s    wait 3 seconds before answering
+//	malformed  answer with a broken JSON body
+func (f faultFlags) Set(value string) error {
+	city, spec, ok := strings.Cut(value, "=")
+	if !ok || city == "" {
+		return fmt.Errorf("expected CITY=SPEC, got %q", value)
+	}
+
+	fault := f[city]
+	switch {
+	case spec == "malformed":
+		fault.Malformed = true
+	case strings.HasPrefix(spec, "slow:"):
+		latency, err := time.ParseDuration(strings.TrimPrefix(spec, "slow:"))
+		if err != nil {
+			return err
+		}
+		fault.Latency = latency
+	default:
+		status, failures, _ := strings.Cut(spec, "/")
+		var err error
+		if fault.Status, err = strconv.Atoi(status); err != nil {
+			return fmt.Errorf("bad status in %q", value)
+		}
+		if failures != "" {
+			if fault.Failures, err = strconv.Atoi(failures); err != nil {
+				return fmt.Errorf("bad failure count in %q", value)
+			}
+		}
+	}
+	f[city] = fault
+	return nil
+}
+
+func main() {
+	addr := flag.String("addr", "localhost:8081", "address to listen on")
+	key := flag.String("key", "", "API key to require, empty accepts any")
+	latency := flag.Duration("latency", 0, "latency added to every request")
+	synthesize := flag.Bool("synthesize", false, "invent data for unknown cities instead of answering 404")
+	faults := faultFlags{}
+	flag.Var(faults, "fault", "make a city misbehave: CITY=503, CITY=503/2, CITY=slow:3s or CITY=malformed (repeatable)")
+	flag.Parse()
+
+	srv := fakeapi.New()
+	srv.APIKey = *key
+	srv.Latency = *latency
+	srv.Synthesize = *synthesize
+	for city, fault := range faults {
+		srv.SetFault(city, fault)
+	}
+
+	// /webhook stands in for an alert receiver: weather current -rules r.txt -notify http://localhost:8081/webhook
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /webhook", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		log.Printf("webhook: %s", bytes.TrimSpace(body))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.Handle("/", srv)
+
+	log.Printf("fake OpenWeatherMap listening on http://%v", *addr)
+	if err := http.ListenAndServe(*addr, mux); err != nil {
+		log.Println(err)
+		os.Exit(1)
+	}
+}
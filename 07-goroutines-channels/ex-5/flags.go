@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"golang-fast-start/07-goroutines-channels/ex-5/config"
+	"golang-fast-start/07-goroutines-channels/ex-5/weather"
+	"golang-fast-start/07-goroutines-channels/ex-5/weather/fakeapi"
+)
+
+// defaultCities is used when no city was given on the command line.
+var defaultCities = []string{"Toronto", "London", "Paris", "Tokyo", "Istanbul", "Moscow", "Oslo", "Ankara"}
+
+// newClient builds a weather client from the merged configuration.
+func newClient(cfg *config.Config) (*weather.Client, error) {
+	client := weather.NewClient(cfg.APIKey)
+	client.BaseURL = cfg.BaseURL
+	client.Units = cfg.Units
+	client.ResolveNames = cfg.Geocode
+	if cfg.Debug {
+		client.Debug = log.New(os.Stderr, "weather: ", log.Ltime|log.Lmicroseconds)
+	}
+	// Duplicate cities are fetched once: the second goroutine waits for the first one's request.
+	client.Cache = weather.NewCache(cfg.CacheTTL)
+	if cfg.Rate > 0 {
+		// The free tier allows 60 calls per minute, the limiter keeps all goroutines under it.
+		client.Limiter = weather.NewLimiter(cfg.Rate, time.Minute, cfg.Burst)
+	}
+	if cfg.CacheDir != "" {
+		var err error
+		client.DiskCache, err = weather.NewDiskCache(cfg.CacheDir, cfg.CacheTTL)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Metrics != "" {
+		client.Metrics = weather.NewMetrics()
+		serveMetrics(cfg.Metrics, client.Metrics)
+	}
+	return client, nil
+}
+
+// serveMetrics exposes the metrics on http://addr/metrics for as long as the program runs.
+// A one-shot command exits when it's done, so scraping is mostly useful for long runs.
+func serveMetrics(addr string, m *weather.Metrics) {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", m.Handler())
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			fmt.Fprintln(os.Stderr, "metrics:", err)
+		}
+	}()
+}
+
+// newProvider builds the providers of cfg, a Fallback when there are several.
+// A single openweathermap provider is the client itself.
+func newProvider(cfg *config.Config, client *weather.Client) weather.Provider {
+	var chain weather.Fallback
+	for _, name := range cfg.Providers {
+		switch name {
+		case "openweathermap":
+			chain = append(chain, client)
+		case "wttr":
+			wttr := weather.NewWttr()
+			wttr.BaseURL = cfg.WttrURL
+			wttr.Units = cfg.Units
+			chain = append(chain, wttr)
+		case "fake":
+			fake := fakeapi.NewProvider()
+			fake.Units = cfg.Units
+			chain = append(chain, fake)
+		}
+	}
+	if len(chain) == 1 {
+		return chain[0]
+	}
+	return chain
+}
+
+// loadProvider is loadClient for the commands that work with any provider.
+// The client is returned too, for its cache statistics and units.
+func loadProvider(cf *config.Flags) (*weather.Client, weather.Provider, error) {
+	cfg, err := cf.Load()
+	if err != nil {
+		return nil, nil, err
+	}
+	client, err := newClient(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	return client, newProvider(cfg, client), nil
+}
+
+// loadClient loads the configuration registered on a command's FlagSet and builds the client.
+// It is for the commands only OpenWeatherMap can answer, forecast and geocode, so
+// providers has to include it: they would send requests without a key otherwise.
+func loadClient(cf *config.Flags) (*weather.Client, error) {
+	cfg, err := cf.Load()
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(cfg.Providers, "openweathermap") {
+		return nil, fmt.Errorf("this command only works with openweathermap, not with providers %v", strings.Join(cfg.Providers, ","))
+	}
+	return newClient(cfg)
+}
+
+// readCities collects the cities from the arguments, a file or stdin.
+// file "-" and a single "-" argument both mean stdin. The defaults are used only
+// when neither was given, an empty file or stdin is an error.
+func readCities(args []string, file string, stdin io.Reader) ([]string, error) {
+	if len(args) == 1 && args[0] == "-" {
+		args, file = nil, "-"
+	}
+
+	if len(args) == 0 && file == "" {
+		return defaultCities, nil
+	}
+
+	cities := append([]string{}, args...)
+	switch file {
+	case "":
+	case "-":
+		if stdin == nil {
+			return nil, errors.New("stdin is not available here, use a file")
+		}
+		fromStdin, err := readCityList(stdin)
+		if err != nil {
+			return nil, fmt.Errorf("reading cities from stdin: %w", err)
+		}
+		if len(fromStdin) == 0 {
+			return nil, errors.New("no cities on stdin")
+		}
+		cities = append(cities, fromStdin...)
+	default:
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		fromFile, err := readCityList(f)
+		if err != nil {
+			return nil, fmt.Errorf("reading %v: %w", file, err)
+		}
+		if len(fromFile) == 0 {
+			return nil, fmt.Errorf("no cities in %v", file)
+		}
+		cities = append(cities, fromFile...)
+	}
+	return cities, nil
+}
+
+// readCityList reads one city per line, blank lines and # comments are skipped.
+func readCityList(r io.Reader) ([]string, error) {
+	var cities []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		cities = append(cities, line)
+	}
+	return cities, scanner.Err()
+}
+
+// parseFlags parses args and turns -help into a clean exit.
+// ok is false when the command should stop and return code.
+func parseFlags(fs *flag.FlagSet, args []string) (code int, ok bool) {
+	err := fs.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK, false
+	}
+	if err != nil {
+		return exitUsage, false // the flag package already printed the error and usage
+	}
+	return exitOK, true
+}
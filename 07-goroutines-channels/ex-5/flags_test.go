@@ -0,0 +1,65 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestReadCities(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	list := write("cities.txt", "# capitals\nParis\n\nTokyo\n")
+	empty := write("empty.txt", "# nothing here\n\n")
+
+	tests := []struct {
+		name     string
+		args     []string
+		file     string
+		stdin    string
+		nilStdin bool // like the daemon, which has no stdin to read
+		want     []string
+		err      bool
+	}{
+		{name: "defaults", want: defaultCities},
+		{name: "arguments", args: []string{"Oslo", "Ankara"}, want: []string{"Oslo", "Ankara"}},
+		{name: "file and arguments", args: []string{"Oslo"}, file: list, want: []string{"Oslo", "Paris", "Tokyo"}},
+		{name: "stdin", file: "-", stdin: "London\n", want: []string{"London"}},
+		{name: "dash argument", args: []string{"-"}, stdin: "London\n", want: []string{"London"}},
+		{name: "empty file", file: empty, err: true},
+		{name: "empty file with arguments", args: []string{"Oslo"}, file: empty, err: true},
+		{name: "empty stdin", file: "-", err: true},
+		{name: "no stdin", args: []string{"-"}, nilStdin: true, err: true},
+		{name: "missing file", file: filepath.Join(dir, "missing.txt"), err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdin io.Reader = strings.NewReader(tt.stdin)
+			if tt.nilStdin {
+				stdin = nil
+			}
+			got, err := readCities(tt.args, tt.file, stdin)
+			if tt.err {
+				if err == nil {
+					t.Errorf("got %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"golang-fast-start/07-goroutines-channels/ex-5/config"
+	"golang-fast-start/07-goroutines-channels/ex-5/weather"
+)
+
+// runForecast fetches the 5 day forecast of every city concurrently and prints daily min/max/avg.
+func runForecast(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("forecast", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: weather forecast [flags] [city ...]")
+		fs.PrintDefaults()
+	}
+
+	cf := config.Register(fs)
+	file := fs.String("file", "", `read cities from a file, one per line ("-" for stdin)`)
+	concurrency := fs.Int("concurrency", 4, "number of concurrent requests, 0 starts one goroutine per city")
+	timeout := fs.Duration("timeout", 10*time.Second, "deadline for the whole run")
+	cityTimeout := fs.Duration("city-timeout", 5*time.Second, "deadline for a single city")
+	format := fs.String("format", "text", "output format: text or json")
+	order := fs.String("order", "input", "result order: arrival (as they complete), input, name or temp")
+	failFast := fs.Bool("fail-fast", false, "cancel the remaining cities as soon as one fails")
+
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(stderr, "Error: unknown format %q, use text or json\n", *format)
+		return exitUsage
+	}
+
+	resultOrder, err := weather.ParseOrder(*order)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error:", err)
+		return exitUsage
+	}
+
+	cities, err := readCities(fs.Args(), *file, stdin)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error:", err)
+		return exitUsage
+	}
+
+	client, err := loadClient(cf)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error:", err)
+		return exitUsage
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	opts := weather.FanOutOptions{CityTimeout: *cityTimeout, Workers: *concurrency, Order: resultOrder, FailFast: *failFast}
+	enc := json.NewEncoder(stdout)
+	code := exitOK
+
+	for result := range client.FetchForecasts(ctx, cities, opts) {
+		if result.Err != nil {
+			code = exitFailed
+		}
+		if *format == "json" {
+			enc.Encode(newForecastRecord(result))
+			continue
+		}
+
+		if result.Err != nil {
+			fmt.Fprintf(stdout, "Error: %v: %v\n", result.City, result.Err)
+			continue
+		}
+		fmt.Fprintf(stdout, "%v, %v\n", result.Data.City.Name, result.Data.City.Country)
+		for _, day := range result.Data.Daily() {
+			fmt.Fprintf(stdout, "  %v  min %v  max %v  avg %v\n", day.Date.Format("Mon 2006-01-02"), day.Min.Format(1), day.Max.Format(1), day.Avg.Format(1))
+		}
+	}
+
+	return code
+}
+
+// forecastRecord is the JSON lines shape of one city's forecast.
+type forecastRecord struct {
+	City  string      `json:"city"`
+	Name  string      `json:"name,omitempty"`
+	Unit  string      `json:"unit,omitempty"`
+	Days  []dayRecord `json:"days,omitempty"`
+	Error string      `json:"error,omitempty"`
+}
+
+type dayRecord struct {
+	Date    string  `json:"date"`
+	Min     float64 `json:"min"`
+	Max     float64 `json:"max"`
+	Avg     float64 `json:"avg"`
+	Entries int     `json:"entries"`
+}
+
+func newForecastRecord(result weather.ForecastResult) forecastRecord {
+	rec := forecastRecord{City: result.City}
+	if result.Err != nil {
+		rec.Error = result.Err.Error()
+		return rec
+	}
+
+	rec.Name = result.Data.City.Name
+	for _, day := range result.Data.Daily() {
+		rec.Unit = day.Avg.Unit.String()
+		rec.Days = append(rec.Days, dayRecord{
+			Date:    day.Date.Format(time.DateOnly),
+			Min:     weather.Round2(day.Min.Value),
+			Max:     weather.Round2(day.Max.Value),
+			Avg:     weather.Round2(day.Avg.Value),
+			Entries: day.Entries,
+		})
+	}
+	return rec
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"golang-fast-start/07-goroutines-channels/ex-5/config"
+)
+
+// runGeocode prints the candidates for each name, handy to pick the right city ID or
+// coordinates when a name is ambiguous.
+func runGeocode(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("geocode", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, `Usage: weather geocode [flags] "City[,State][,CountryCode]" ...`)
+		fs.PrintDefaults()
+	}
+
+	cf := config.Register(fs)
+	limit := fs.Int("limit", 5, "maximum number of places per name")
+	timeout := fs.Duration("timeout", 10*time.Second, "deadline for the whole run")
+
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	client, err := loadClient(cf)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error:", err)
+		return exitUsage
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	code := exitOK
+	for _, name := range fs.Args() {
+		places, err := client.Geocode(ctx, name, *limit)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v: %v\n", name, err)
+			code = exitFailed
+			continue
+		}
+		if len(places) == 0 {
+			fmt.Fprintf(stderr, "No place found for %v\n", name)
+			code = exitFailed
+			continue
+		}
+
+		fmt.Fprintf(stdout, "%v:\n", name)
+		for _, place := range places {
+			parts := []string{place.Name}
+			if place.State != "" {
+				parts = append(parts, place.State)
+			}
+			parts = append(parts, place.Country)
+			// the coordinates can be passed back as a city: weather 51.5085,-0.1257
+			fmt.Fprintf(stdout, "  %-40v %.4f,%.4f\n", strings.Join(parts, ", "), place.Lat, place.Lon)
+		}
+	}
+	return code
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"golang-fast-start/07-goroutines-channels/ex-5/config"
+	"golang-fast-start/07-goroutines-channels/ex-5/store"
+)
+
+// runHistory prints what the daemon stored, for one city or all of them, over a time range.
+func runHistory(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: weather history [flags] [city]")
+		fs.PrintDefaults()
+	}
+
+	cf := config.Register(fs)
+	cf.APIKeyOptional = true // reads the store only
+	since := fs.Duration("since", 24*time.Hour, "show observations of this long ago until now, ignored with -from")
+	from := fs.String("from", "", "start of the range, a date (2026-10-01) or RFC 3339 time")
+	to := fs.String("to", "", "end of the range (exclusive), same forms as -from, empty means now")
+	format := fs.String("format", "text", "output format: text or json")
+
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(stderr, "Error: unknown format %q, use text or json\n", *format)
+		return exitUsage
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	start, err := parseTime(*from)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error: -from:", err)
+		return exitUsage
+	}
+	end, err := parseTime(*to)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error: -to:", err)
+		return exitUsage
+	}
+	if start.IsZero() && *since > 0 {
+		start = time.Now().Add(-*since)
+	}
+
+	cfg, err := cf.Load()
+	if err != nil {
+		fmt.Fprintln(stderr, "Error:", err)
+		return exitUsage
+	}
+	db, err := store.Open(cfg.DataDir)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error:", err)
+		return exitFailed
+	}
+
+	observations, err := db.Query(fs.Arg(0), start, end)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error:", err)
+		return exitFailed
+	}
+
+	if *format == "json" {
+		enc := json.NewEncoder(stdout)
+		for _, obs := range observations {
+			enc.Encode(obs)
+		}
+		return exitOK
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "OBSERVED\tCITY\tTEMP\tHUMIDITY\tWIND\tCONDITIONS")
+	for _, obs := range observations {
+		observed := obs.ObservedAt.Local().Format("2006-01-02 15:04")
+		if obs.Error != "" {
+			fmt.Fprintf(w, "%v\t%v\t-\t-\t-\terror: %v\n", observed, obs.City, obs.Error)
+			continue
+		}
+		temp := "-" // a line written by hand or by an older version may lack it
+		if obs.Temp != nil {
+			temp = fmt.Sprintf("%.1f%v", *obs.Temp, obs.Unit)
+		}
+		fmt.Fprintf(w, "%v\t%v\t%v\t%d%%\t%.1f\t%v\n", observed, obs.City, temp, obs.Humidity, obs.WindSpeed, obs.Conditions)
+	}
+	w.Flush()
+	fmt.Fprintf(stderr, "%d observations\n", len(observations))
+	return exitOK
+}
+
+// runCompact merges old daily segments of the store into monthly ones.
+func runCompact(args []string, stderr io.Writer) int {
+	fs := flag.NewFlagSet("compact", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: weather compact [flags]")
+		fs.PrintDefaults()
+	}
+
+	cf := config.Register(fs)
+	cf.APIKeyOptional = true
+	olderThan := fs.Duration("older-than", 7*24*time.Hour, "compact the days that ended longer ago than this")
+
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	cfg, err := cf.Load()
+	if err != nil {
+		fmt.Fprintln(stderr, "Error:", err)
+		return exitUsage
+	}
+	db, err := store.Open(cfg.DataDir)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error:", err)
+		return exitFailed
+	}
+
+	stats, err := db.Compact(time.Now().Add(-*olderThan))
+	if err != nil {
+		fmt.Fprintln(stderr, "Error:", err)
+		return exitFailed
+	}
+	fmt.Fprintf(stderr, "Merged %d daily segments into %d months, %d observations kept, %d duplicates dropped\n",
+		stats.Segments, stats.Months, stats.Kept, stats.Duplicates)
+	return exitOK
+}
+
+// parseTime accepts a date or an RFC 3339 time, "" gives the zero time (an open range).
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither a date like 2026-10-01 nor a time like 2026-10-01T15:04:05Z", value)
+	}
+	return t, nil
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHistoryWithoutTemperature(t *testing.T) {
+	dir := t.TempDir()
+	// written by hand: no temp, which the text output used to dereference
+	line := `{"observed_at":"2026-10-17T12:00:00Z","city":"Oslo","humidity":80,"conditions":"fog"}` + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "2026-10-17.jsonl"), []byte(line), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	code := runHistory([]string{"-data-dir", dir, "-from", "2026-10-17", "-to", "2026-10-18"}, &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("exit %d: %v", code, stderr.String())
+	}
+	fields := strings.Fields(strings.Split(stdout.String(), "\n")[1])
+	if len(fields) < 5 || fields[2] != "Oslo" || fields[3] != "-" {
+		t.Errorf("row %q, want Oslo with - for the temperature", fields)
+	}
+}
@@ -1,88 +1,102 @@
 package main
 
 import (
-	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
-	"net/http"
 	"os"
-	"time"
 
-	"github.com/joho/godotenv"
+	"golang-fast-start/07-goroutines-channels/ex-5/config"
 )
 
-// Get API Key from the https://openweathermap.org/ and locate in .env file
-// http://api.openweathermap.org/data/2.5/weather?q={CITY}&appid={API_KEY}
+// Get API Key from the https://openweathermap.org/ and locate in .env file (or see the config package)
+// The HTTP details live in the weather package, this package is the command line front end:
+//
+//	go run ./07-goroutines-channels/ex-5 London Paris Tokyo
+//	go run ./07-goroutines-channels/ex-5 current -units imperial -file cities.txt
+//	cat cities.txt | go run ./07-goroutines-channels/ex-5 current -
+
+// Exit codes, so scripts can tell a partial failure from a misuse.
+const (
+	exitOK     = 0
+	exitFailed = 1 // at least one city could not be fetched
+	exitUsage  = 2 // bad flags, arguments or configuration
+)
 
-type WeatherResponse struct {
-	Main struct {
-		Temp float64 `json:"temp"`
-	} `json:"main"`
-	Name string `json:"name"`
-}
-
-type WeatherResult struct {
-	Data WeatherResponse
-	Err  error
-}
+const usage = `Usage: weather [command] [flags] [city ...]
+
+Commands:
+  current       fetch the current weather of every city concurrently (default)
+  forecast      fetch the 5 day forecast of every city and print daily min/max/avg
+                (OpenWeatherMap only)
+  daemon        poll the cities on an interval and append every observation
+  serve         answer GET /weather?city= and /weather/batch over HTTP as JSON
+  history       show the observations the daemon stored
+  compact       merge old daily segments of the observation store into months
+  geocode       list the places a city name resolves to (OpenWeatherMap only)
+  purge-cache   remove stale entries from the on-disk cache
+  config        show the effective configuration and where each value comes from
+  help          show this help
+
+Cities are read from the arguments, from -file, or from stdin when the only
+argument is "-". Without any city a small built-in list is used. A city is a
+name ("São Paulo"), a name with country code ("London,GB"), a city ID
+("id:2643743") or coordinates ("51.5085,-0.1257").
+
+Run "weather <command> -help" for the flags of a command.
+`
 
 func main() {
-	godotenv.Load()
-	apiKey := os.Getenv("OPENWEATHER_API_KEY")
-
-	if apiKey == "" {
-		fmt.Printf("Can not get API KEY")
-		return
-	}
-
-	cities := []string{"Toronto", "London", "Paris", "Tokyo", "Istanbul", "Moscow", "Oslo", "Ankara"}
-
-	ch := make(chan WeatherResult)
-
-	startTime := time.Now()
-
-	// Launch all goroutines
-	for _, city := range cities {
-		go fetchWeather(city, apiKey, ch)
-	}
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
 
-	// Collect results (one receive per goroutine)
-	for range cities {
-		result := <-ch
-		if result.Err != nil {
-			fmt.Println("Error:", result.Err)
-			continue
+// run dispatches to a subcommand and returns the process exit code.
+// Anything that isn't a known command is treated as the arguments of "current",
+// so "weather London Paris" works without spelling out the command.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case "current":
+			return runCurrent(args[1:], stdin, stdout, stderr)
+		case "forecast":
+			return runForecast(args[1:], stdin, stdout, stderr)
+		case "daemon":
+			return runDaemon(args[1:], stdout, stderr)
+		case "serve":
+			return runServe(args[1:], stderr)
+		case "history":
+			return runHistory(args[1:], stdout, stderr)
+		case "compact":
+			return runCompact(args[1:], stderr)
+		case "geocode":
+			return runGeocode(args[1:], stdout, stderr)
+		case "purge-cache":
+			return runPurgeCache(args[1:], stderr)
+		case "config":
+			return runConfig(args[1:], stdout, stderr)
+		case "help", "-h", "-help", "--help":
+			fmt.Fprint(stdout, usage)
+			return exitOK
 		}
-		fmt.Printf("City: %v, Temperature: %v\n", result.Data.Name, result.Data.Main.Temp)
 	}
-
-	close(ch)
-
-	fmt.Printf("Time taken to fetch all cities: %v", time.Since(startTime))
+	return runCurrent(args, stdin, stdout, stderr)
 }
 
-func fetchWeather(city string, apiKey string, ch chan<- WeatherResult) {
-	url := fmt.Sprintf("http://api.openweathermap.org/data/2.5/weather?q=%s&appid=%s", city, apiKey)
-
-	resp, err := http.Get(url)
-	if err != nil {
-		ch <- WeatherResult{Err: err} // send error through channel
-		return
-	}
+// runConfig prints the merged configuration, the API key redacted.
+func runConfig(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	cf := config.Register(fs)
+	cf.APIKeyOptional = true // showing that it is missing is the point
 
-	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		ch <- WeatherResult{Err: err}
-		return
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
 	}
-
-	var data WeatherResponse
-	err = json.Unmarshal(body, &data)
+	cfg, err := cf.Load()
 	if err != nil {
-		ch <- WeatherResult{Err: err}
-		return
+		fmt.Fprintln(stderr, "Error:", err)
+		return exitUsage
 	}
-
-	ch <- WeatherResult{Data: data} // send success through channel
+	fmt.Fprintln(stdout, cfg)
+	return exitOK
 }
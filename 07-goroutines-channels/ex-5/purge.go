@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"golang-fast-start/07-goroutines-channels/ex-5/config"
+	"golang-fast-start/07-goroutines-channels/ex-5/weather"
+)
+
+// runPurgeCache removes old entries from the on-disk cache.
+func runPurgeCache(args []string, stderr io.Writer) int {
+	fs := flag.NewFlagSet("purge-cache", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: weather purge-cache [flags]")
+		fs.PrintDefaults()
+	}
+
+	cf := config.Register(fs)
+	cf.APIKeyOptional = true // purging never talks to the API
+	maxAge := fs.Duration("max-age", 24*time.Hour, "remove entries fetched longer ago than this")
+
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	cfg, err := cf.Load()
+	if err != nil {
+		fmt.Fprintln(stderr, "Error:", err)
+		return exitUsage
+	}
+	if cfg.CacheDir == "" {
+		fmt.Fprintln(stderr, "Error: no cache directory, set -cache-dir, WEATHER_CACHE_DIR or \"cache_dir\" in the config file")
+		return exitUsage
+	}
+
+	removed, err := (&weather.DiskCache{Dir: cfg.CacheDir}).Purge(*maxAge)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error:", err)
+		return exitFailed
+	}
+	fmt.Fprintf(stderr, "Removed %d stale cache entries from %v\n", removed, cfg.CacheDir)
+	return exitOK
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"golang-fast-start/07-goroutines-channels/ex-5/config"
+	"golang-fast-start/07-goroutines-channels/ex-5/weather"
+)
+
+// runServe answers weather questions over HTTP so other programs don't need their own API key:
+//
+//	GET /weather?city=London                  one city, 200 or the error status
+//	GET /weather/batch?city=London&city=Paris   many cities fetched concurrently, always 200
+//	                                             with an "error" field on the cities that failed
+//
+// Both are served from the same client, so the cache and the rate limit are shared by
+// every caller. The metrics are always on, at GET /metrics of the same address.
+// SIGINT/SIGTERM stop accepting connections and give the running requests -grace to finish.
+func runServe(args []string, stderr io.Writer) int {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: weather serve [flags]")
+		fs.PrintDefaults()
+	}
+
+	cf := config.Register(fs)
+	addr := fs.String("addr", "localhost:8080", "listen address")
+	concurrency := fs.Int("concurrency", 4, "concurrent API requests of one batch, 0 starts one goroutine per city")
+	requestTimeout := fs.Duration("request-timeout", 10*time.Second, "deadline for answering one HTTP request")
+	cityTimeout := fs.Duration("city-timeout", 5*time.Second, "deadline for a single city")
+	maxBatch := fs.Int("max-batch", 50, "most cities accepted by /weather/batch")
+	grace := fs.Duration("grace", 10*time.Second, "how long running requests may finish on shutdown")
+	cacheSize := fs.Int("cache-size", weather.DefaultCacheEntries, "most responses kept in the in-memory cache")
+
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	cfg, err := cf.Load()
+	if err != nil {
+		fmt.Fprintln(stderr, "Error:", err)
+		return exitUsage
+	}
+	logger := log.New(stderr, "serve: ", log.LstdFlags)
+	if cfg.Metrics != "" {
+		// one listener is enough, newClient would start a second one
+		logger.Printf("metrics are served on %v/metrics, ignoring metrics-addr %v", *addr, cfg.Metrics)
+		cfg.Metrics = ""
+	}
+	client, err := newClient(cfg)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error:", err)
+		return exitUsage
+	}
+	client.Metrics = weather.NewMetrics()
+	// callers choose the cities, so keep what they can make us remember bounded
+	client.Cache.MaxEntries = *cacheSize
+	provider := newProvider(cfg, client)
+
+	s := &server{
+		client:         client,
+		provider:       provider,
+		opts:           weather.FanOutOptions{CityTimeout: *cityTimeout, Workers: *concurrency, Order: weather.OrderInput},
+		requestTimeout: *requestTimeout,
+		maxBatch:       *maxBatch,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /weather", s.handleWeather)
+	mux.HandleFunc("GET /weather/batch", s.handleBatch)
+	mux.Handle("GET /metrics", client.Metrics.Handler())
+
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           logRequests(mux, logger),
+		ReadHeaderTimeout: 5 * time.Second,
+		// a bit longer than the handler deadline, so a slow answer is still written
+		WriteTimeout: *requestTimeout + 5*time.Second,
+		IdleTimeout:  time.Minute,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// ListenAndServe returns ErrServerClosed as soon as Shutdown is called, the
+	// running requests are finished by Shutdown itself
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- srv.ListenAndServe() }()
+	logger.Printf("listening on http://%v", *addr)
+
+	select {
+	case err := <-serveErr:
+		fmt.Fprintln(stderr, "Error:", err)
+		return exitFailed
+	case <-ctx.Done():
+	}
+
+	logger.Printf("shutting down, waiting up to %v for running requests", *grace)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), *grace)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Print("shutdown: ", err)
+		return exitFailed
+	}
+	return exitOK
+}
+
+// server holds what the handlers share.
+type server struct {
+	client         *weather.Client
+	provider       weather.Provider
+	opts           weather.FanOutOptions
+	requestTimeout time.Duration
+	maxBatch       int
+}
+
+// weatherResponse is the body of /weather, and one element of /weather/batch.
+type weatherResponse struct {
+	weather.Record
+	Category string `json:"error_category,omitempty"`
+}
+
+type batchResponse struct {
+	Results   []weatherResponse `json:"results"`
+	Failed    int               `json:"failed"`
+	ElapsedMS int64             `json:"elapsed_ms"`
+}
+
+type errorResponse struct {
+	Error    string `json:"error"`
+	Category string `json:"error_category,omitempty"`
+}
+
+func (s *server) handleWeather(w http.ResponseWriter, r *http.Request) {
+	city := r.URL.Query().Get("city")
+	if city == "" {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "missing city parameter, e.g. /weather?city=London"})
+		return
+	}
+
+	// the request context is cancelled too when the caller hangs up
+	ctx, cancel := context.WithTimeout(r.Context(), s.requestTimeout)
+	defer cancel()
+
+	// a batch of one goes through the same fan-out, city timeout and retries included
+	result := <-weather.FetchAllFrom(ctx, s.provider, []string{city}, s.opts)
+	if result.Err != nil {
+		status := statusFor(result.Err)
+		if retryAfter := retryAfterOf(result.Err); retryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds()+0.5)))
+		}
+		writeJSON(w, status, errorResponse{Error: result.Err.Error(), Category: weather.ErrorCategory(result.Err)})
+		return
+	}
+	writeJSON(w, http.StatusOK, weatherResponse{Record: weather.NewRecord(result, s.client.Units.TempUnit())})
+}
+
+func (s *server) handleBatch(w http.ResponseWriter, r *http.Request) {
+	// repeated parameters instead of a comma separated list, "London,GB" is one city
+	cities := r.URL.Query()["city"]
+	switch {
+	case len(cities) == 0:
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "missing city parameter, e.g. /weather/batch?city=London&city=Paris"})
+		return
+	case len(cities) > s.maxBatch:
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: fmt.Sprintf("%d cities, at most %d per batch", len(cities), s.maxBatch)})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.requestTimeout)
+	defer cancel()
+
+	start := time.Now()
+	resp := batchResponse{Results: make([]weatherResponse, 0, len(cities))}
+	for result := range weather.FetchAllFrom(ctx, s.provider, cities, s.opts) {
+		item := weatherResponse{Record: weather.NewRecord(result, s.client.Units.TempUnit())}
+		if result.Err != nil {
+			item.Category = weather.ErrorCategory(result.Err)
+			resp.Failed++
+		}
+		resp.Results = append(resp.Results, item)
+	}
+	resp.ElapsedMS = time.Since(start).Milliseconds()
+
+	// the batch itself worked even when some cities didn't, those carry their own error
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// statusFor maps a fetch error to the status our callers get. Problems with the upstream
+// API are 502, except the ones a caller can act on: a city that doesn't exist is 404,
+// being rate limited is 503 and running out of time is 504.
+func statusFor(err error) int {
+	switch {
+	case errors.Is(err, weather.ErrCityNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, weather.ErrRateLimited):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, context.Canceled):
+		return 499 // nginx's "client closed request", the caller is gone and it only shows in the log
+	}
+	return http.StatusBadGateway
+}
+
+func retryAfterOf(err error) time.Duration {
+	var apiErr *weather.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.RetryAfter
+	}
+	return 0
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// statusRecorder remembers the status code for the access log.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// logRequests writes one access log line per request.
+func logRequests(next http.Handler, logger *log.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		logger.Printf("%v %v %d %v", r.Method, r.URL.RequestURI(), rec.status, time.Since(start).Round(time.Millisecond))
+	})
+}
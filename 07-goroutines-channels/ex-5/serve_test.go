@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"golang-fast-start/07-goroutines-channels/ex-5/weather"
+	"golang-fast-start/07-goroutines-channels/ex-5/weather/fakeapi"
+)
+
+// newTestServer returns the handlers of "weather serve" backed by fake.
+func newTestServer(t *testing.T, fake *fakeapi.Server) *server {
+	t.Helper()
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	client := weather.NewClient("test-key")
+	client.BaseURL = srv.URL
+	client.Retry = weather.RetryPolicy{} // one attempt, a 429 would wait for its Retry-After
+	return &server{
+		client:         client,
+		provider:       client,
+		opts:           weather.FanOutOptions{CityTimeout: 100 * time.Millisecond, Workers: 2, Order: weather.OrderInput},
+		requestTimeout: time.Second,
+		maxBatch:       3,
+	}
+}
+
+func get(handler http.HandlerFunc, path string, cities ...string) *httptest.ResponseRecorder {
+	query := url.Values{"city": cities}
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, path+"?"+query.Encode(), nil))
+	return rec
+}
+
+func TestStatusFor(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{fmt.Errorf("fetching: %w", weather.ErrCityNotFound), http.StatusNotFound},
+		{fmt.Errorf("fetching: %w", weather.ErrRateLimited), http.StatusServiceUnavailable},
+		{fmt.Errorf("%w (last attempt: %w)", context.DeadlineExceeded, weather.ErrServer), http.StatusGatewayTimeout},
+		{context.Canceled, 499},
+		{weather.ErrServer, http.StatusBadGateway},
+		{weather.ErrUnauthorized, http.StatusBadGateway}, // our key, nothing the caller can fix
+	}
+	for _, tt := range tests {
+		if got := statusFor(tt.err); got != tt.want {
+			t.Errorf("statusFor(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestHandleWeather(t *testing.T) {
+	fake := fakeapi.New()
+	fake.SetFault("Oslo", fakeapi.Fault{Status: http.StatusTooManyRequests, RetryAfter: "7"})
+	fake.SetFault("Paris", fakeapi.Fault{Latency: time.Second})
+	s := newTestServer(t, fake)
+
+	tests := []struct {
+		name       string
+		cities     []string
+		status     int
+		category   string
+		retryAfter string
+	}{
+		{"found", []string{"London"}, http.StatusOK, "", ""},
+		{"missing city", nil, http.StatusBadRequest, "", ""},
+		{"unknown city", []string{"Nowhere"}, http.StatusNotFound, "not_found", ""},
+		{"rate limited", []string{"Oslo"}, http.StatusServiceUnavailable, "rate_limited", "7"},
+		{"city timeout", []string{"Paris"}, http.StatusGatewayTimeout, "timeout", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := get(s.handleWeather, "/weather", tt.cities...)
+			if rec.Code != tt.status {
+				t.Errorf("status %d, want %d: %v", rec.Code, tt.status, rec.Body)
+			}
+			if got := rec.Header().Get("Retry-After"); got != tt.retryAfter {
+				t.Errorf("Retry-After %q, want %q", got, tt.retryAfter)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type %q", ct)
+			}
+
+			var body struct {
+				Name     string   `json:"name"`
+				Temp     *float64 `json:"temp"`
+				Error    string   `json:"error"`
+				Category string   `json:"error_category"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("%v: %q", err, rec.Body)
+			}
+			if body.Category != tt.category {
+				t.Errorf("category %q, want %q", body.Category, tt.category)
+			}
+			if tt.status == http.StatusOK && (body.Name != "London" || body.Temp == nil || body.Error != "") {
+				t.Errorf("body %+v, want London's weather", body)
+			}
+			if tt.status != http.StatusOK && body.Error == "" {
+				t.Error("no error message")
+			}
+		})
+	}
+}
+
+func TestHandleBatch(t *testing.T) {
+	fake := fakeapi.New()
+	fake.SetFault("Paris", fakeapi.Fault{Status: http.StatusServiceUnavailable})
+	s := newTestServer(t, fake)
+
+	for _, cities := range [][]string{nil, {"London", "Paris", "Tokyo", "Oslo"}} {
+		rec := get(s.handleBatch, "/weather/batch", cities...)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%d cities: status %d, want 400", len(cities), rec.Code)
+		}
+	}
+
+	rec := get(s.handleBatch, "/weather/batch", "London", "Paris", "Nowhere")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d, a batch with failed cities is still 200: %v", rec.Code, rec.Body)
+	}
+	var resp batchResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Failed != 2 || len(resp.Results) != 3 {
+		t.Fatalf("%d results, %d failed, want 3 and 2", len(resp.Results), resp.Failed)
+	}
+	var got []string
+	for _, r := range resp.Results {
+		got = append(got, r.City+":"+r.Category)
+	}
+	if want := "London: Paris:server Nowhere:not_found"; strings.Join(got, " ") != want {
+		t.Errorf("results %q, want the input order %q", got, want)
+	}
+}
@@ -0,0 +1,343 @@
+// Package store keeps every weather observation on disk so trends can be charted later,
+// without a database: an append-only log of JSON lines under a data directory.
+//
+// The log is split in segments by UTC date:
+//
+//	data/2026-10-18.jsonl   one day, appended to while that day lasts
+//	data/2026-09.jsonl      a whole month, written by Compact
+//
+// Writes only ever append whole lines, so a crash can at worst leave a half line at the
+// end of the current segment; readers skip lines they can't decode. Range queries look
+// at the segment names first and only open the ones that overlap the range.
+package store
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang-fast-start/07-goroutines-channels/ex-5/weather"
+)
+
+// Observation is one stored result: the flattened weather plus when it was taken.
+type Observation struct {
+	ObservedAt time.Time  `json:"observed_at"`           // when we asked for it
+	MeasuredAt *time.Time `json:"measured_at,omitempty"` // the "dt" of the response, when the station measured it
+	weather.Record
+}
+
+// NewObservation flattens r as observed at the given time, temperatures in unit.
+func NewObservation(r weather.WeatherResult, unit weather.TempUnit, at time.Time) Observation {
+	obs := Observation{ObservedAt: at.UTC(), Record: weather.NewRecord(r, unit)}
+	if r.Err == nil && !r.Data.Time.IsZero() {
+		measured := r.Data.Time.UTC()
+		obs.MeasuredAt = &measured
+	}
+	return obs
+}
+
+// Segment name layouts, the file name without ".jsonl" parses with one of them.
+const (
+	dayLayout   = "2006-01-02"
+	monthLayout = "2006-01"
+	segmentExt  = ".jsonl"
+)
+
+// Store is an open data directory. It is safe for concurrent use by the goroutines of
+// one process; two processes appending to the same directory is not supported.
+type Store struct {
+	dir string
+
+	mu      sync.Mutex
+	day     string   // date of the open segment
+	current *os.File // open segment of day, nil until the first Append
+}
+
+// Open creates dir if needed and returns the store in it.
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Store{dir: dir}, nil
+}
+
+// Append writes the observations to the segment of their day.
+func (s *Store) Append(observations ...Observation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, obs := range observations {
+		line, err := json.Marshal(obs)
+		if err != nil {
+			return err
+		}
+		f, err := s.segmentFor(obs.ObservedAt)
+		if err != nil {
+			return err
+		}
+		// one Write per line, with O_APPEND the line lands in one piece at the end
+		if _, err := f.Write(append(line, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// segmentFor returns the open daily segment of t, switching files when the day changed.
+func (s *Store) segmentFor(t time.Time) (*os.File, error) {
+	day := t.UTC().Format(dayLayout)
+	if s.current != nil && s.day == day {
+		return s.current, nil
+	}
+	if s.current != nil {
+		s.current.Close()
+		s.current = nil
+	}
+	f, err := os.OpenFile(filepath.Join(s.dir, day+segmentExt), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	s.day, s.current = day, f
+	return f, nil
+}
+
+// Close closes the open segment. The store can still be used, the next Append reopens it.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.current == nil {
+		return nil
+	}
+	err := s.current.Close()
+	s.current = nil
+	return err
+}
+
+// Query returns the observations of city taken in [from, to), oldest first.
+// city matches the requested name or the name the API answered with, ignoring case;
+// "" means every city. A zero from or to leaves that end of the range open.
+func (s *Store) Query(city string, from, to time.Time) ([]Observation, error) {
+	segments, err := s.segments()
+	if err != nil {
+		return nil, err
+	}
+
+	var found []Observation
+	for _, seg := range segments {
+		if !seg.overlaps(from, to) {
+			continue
+		}
+		err := readSegment(seg.path, func(obs Observation) {
+			if !from.IsZero() && obs.ObservedAt.Before(from) {
+				return
+			}
+			if !to.IsZero() && !obs.ObservedAt.Before(to) {
+				return
+			}
+			if city != "" && !strings.EqualFold(obs.City, city) && !strings.EqualFold(obs.Name, city) {
+				return
+			}
+			found = append(found, obs)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// segments come sorted, but a compacted month and a not yet removed day may overlap
+	slices.SortStableFunc(found, func(a, b Observation) int { return a.ObservedAt.Compare(b.ObservedAt) })
+	return found, nil
+}
+
+// CompactStats reports what Compact did.
+type CompactStats struct {
+	Segments   int // daily segments merged away
+	Months     int // monthly segments written
+	Kept       int // observations in the written months
+	Duplicates int // observations dropped because they were already stored
+}
+
+// Compact merges the daily segments of days before the given time into one segment per
+// month. Along the way it drops duplicates: the API only refreshes a city every few
+// minutes, so frequent polls store the same measurement several times.
+//
+// The month is written to a temporary file and renamed over the old one before the daily
+// segments are removed. A crash in between leaves both, which queries tolerate and the
+// next Compact cleans up.
+func (s *Store) Compact(before time.Time) (CompactStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var stats CompactStats
+	segments, err := s.segments()
+	if err != nil {
+		return stats, err
+	}
+
+	cutoff := before.UTC().Truncate(24 * time.Hour)
+	byMonth := map[string][]segment{}
+	for _, seg := range segments {
+		if seg.daily && seg.start.Before(cutoff) {
+			month := seg.start.Format(monthLayout)
+			byMonth[month] = append(byMonth[month], seg)
+		}
+	}
+
+	for _, month := range slices.Sorted(maps.Keys(byMonth)) {
+		days := byMonth[month]
+		monthPath := filepath.Join(s.dir, month+segmentExt)
+		if s.current != nil && slices.ContainsFunc(days, func(seg segment) bool { return seg.name == s.day }) {
+			s.current.Close()
+			s.current = nil
+		}
+
+		// the existing month first, so a re-run merges into it instead of replacing it
+		var all []Observation
+		collect := func(obs Observation) { all = append(all, obs) }
+		if err := readSegment(monthPath, collect); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return stats, err
+		}
+		for _, seg := range days {
+			if err := readSegment(seg.path, collect); err != nil {
+				return stats, err
+			}
+		}
+
+		kept := dedupe(all)
+		stats.Duplicates += len(all) - len(kept)
+		if err := writeSegment(monthPath, kept); err != nil {
+			return stats, fmt.Errorf("compacting %v: %w", month, err)
+		}
+		stats.Months++
+		stats.Kept += len(kept)
+
+		for _, seg := range days {
+			if err := os.Remove(seg.path); err != nil {
+				return stats, err
+			}
+			stats.Segments++
+		}
+	}
+	return stats, nil
+}
+
+// dedupe sorts by time and keeps the first observation of every city and measurement.
+// Failed fetches have no measurement and are all kept.
+func dedupe(all []Observation) []Observation {
+	slices.SortStableFunc(all, func(a, b Observation) int { return a.ObservedAt.Compare(b.ObservedAt) })
+
+	seen := map[string]bool{}
+	kept := all[:0]
+	for _, obs := range all {
+		if obs.MeasuredAt != nil {
+			key := strings.ToLower(obs.City) + "|" + obs.Unit + "|" + obs.MeasuredAt.Format(time.RFC3339)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+		}
+		kept = append(kept, obs)
+	}
+	return kept
+}
+
+// segment is one file of the log and the time span its name says it covers.
+type segment struct {
+	name       string // without extension
+	path       string
+	daily      bool
+	start, end time.Time
+}
+
+func (seg segment) overlaps(from, to time.Time) bool {
+	return (to.IsZero() || seg.start.Before(to)) && (from.IsZero() || seg.end.After(from))
+}
+
+// segments lists the segment files in time order, a month before the days it starts
+// with. Other files in the directory are ignored.
+func (s *Store) segments() ([]segment, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var segments []segment
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), segmentExt)
+		if !ok || e.IsDir() {
+			continue
+		}
+		seg := segment{name: name, path: filepath.Join(s.dir, e.Name())}
+		if t, err := time.Parse(dayLayout, name); err == nil {
+			seg.daily, seg.start, seg.end = true, t, t.AddDate(0, 0, 1)
+		} else if t, err := time.Parse(monthLayout, name); err == nil {
+			seg.start, seg.end = t, t.AddDate(0, 1, 0)
+		} else {
+			continue
+		}
+		segments = append(segments, seg)
+	}
+	// not the name order: "2026-10-18.jsonl" sorts before "2026-10.jsonl"
+	slices.SortFunc(segments, func(a, b segment) int {
+		if c := a.start.Compare(b.start); c != 0 {
+			return c
+		}
+		return b.end.Compare(a.end)
+	})
+	return segments, nil
+}
+
+// readSegment calls fn for every observation in the file, skipping lines that don't decode.
+func readSegment(path string, fn func(Observation)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var obs Observation
+		if err := json.Unmarshal(scanner.Bytes(), &obs); err != nil {
+			continue // most likely the half line of a crash
+		}
+		fn(obs)
+	}
+	return scanner.Err()
+}
+
+// writeSegment replaces path with the observations, through a temporary file and a rename.
+func writeSegment(path string, observations []Observation) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // fails harmlessly after the rename
+
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for _, obs := range observations {
+		if err := enc.Encode(obs); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,217 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"golang-fast-start/07-goroutines-channels/ex-5/weather"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// observation of city at, measured by the station at measured ("" for a failed fetch).
+func observation(city, at, measured string) Observation {
+	obs := Observation{ObservedAt: date(at), Record: weather.Record{City: city, Unit: "°C"}}
+	if measured != "" {
+		m := date(measured)
+		obs.MeasuredAt = &m
+	} else {
+		obs.Error = "timeout"
+	}
+	return obs
+}
+
+func openStore(t *testing.T, observations ...Observation) *Store {
+	t.Helper()
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	if err := s.Append(observations...); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func segmentNames(t *testing.T, s *Store) []string {
+	t.Helper()
+	segments, err := s.segments()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, seg := range segments {
+		names = append(names, seg.name)
+	}
+	return names
+}
+
+func cities(observations []Observation) []string {
+	var names []string
+	for _, obs := range observations {
+		names = append(names, obs.City)
+	}
+	return names
+}
+
+func TestSegmentOverlaps(t *testing.T) {
+	day := segment{daily: true, start: date("2026-10-17T00:00:00Z"), end: date("2026-10-18T00:00:00Z")}
+	tests := []struct {
+		name     string
+		from, to string
+		want     bool
+	}{
+		{"open range", "", "", true},
+		{"inside", "2026-10-17T06:00:00Z", "2026-10-17T07:00:00Z", true},
+		{"covers it", "2026-10-01T00:00:00Z", "2026-11-01T00:00:00Z", true},
+		{"ends at its start", "2026-10-16T00:00:00Z", "2026-10-17T00:00:00Z", false},
+		{"starts at its end", "2026-10-18T00:00:00Z", "", false},
+		{"starts just before its end", "2026-10-17T23:59:59Z", "", true},
+		{"open start", "", "2026-10-17T00:00:01Z", true},
+		{"before it", "", "2026-10-16T12:00:00Z", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var from, to time.Time
+			if tt.from != "" {
+				from = date(tt.from)
+			}
+			if tt.to != "" {
+				to = date(tt.to)
+			}
+			if got := day.overlaps(from, to); got != tt.want {
+				t.Errorf("overlaps(%v, %v) = %v, want %v", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryRange(t *testing.T) {
+	s := openStore(t,
+		observation("Oslo", "2026-10-16T23:00:00Z", "2026-10-16T22:50:00Z"),
+		observation("Paris", "2026-10-17T00:00:00Z", "2026-10-16T23:50:00Z"),
+		observation("Oslo", "2026-10-17T12:00:00Z", "2026-10-17T11:50:00Z"),
+		observation("Tokyo", "2026-10-18T00:00:00Z", ""),
+	)
+	if got, want := segmentNames(t, s), []string{"2026-10-16", "2026-10-17", "2026-10-18"}; !slices.Equal(got, want) {
+		t.Fatalf("segments %v, want %v", got, want)
+	}
+
+	tests := []struct {
+		name     string
+		city     string
+		from, to string
+		want     []string
+	}{
+		{"everything", "", "", "", []string{"Oslo", "Paris", "Oslo", "Tokyo"}},
+		{"from is inclusive", "", "2026-10-17T00:00:00Z", "", []string{"Paris", "Oslo", "Tokyo"}},
+		{"to is exclusive", "", "", "2026-10-18T00:00:00Z", []string{"Oslo", "Paris", "Oslo"}},
+		{"one day", "", "2026-10-17T00:00:00Z", "2026-10-18T00:00:00Z", []string{"Paris", "Oslo"}},
+		{"empty range", "", "2026-10-17T00:00:00Z", "2026-10-17T00:00:00Z", nil},
+		{"one city, any case", "oslo", "", "", []string{"Oslo", "Oslo"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var from, to time.Time
+			if tt.from != "" {
+				from = date(tt.from)
+			}
+			if tt.to != "" {
+				to = date(tt.to)
+			}
+			found, err := s.Query(tt.city, from, to)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := cities(found); !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuerySkipsBrokenLines(t *testing.T) {
+	s := openStore(t, observation("Oslo", "2026-10-17T12:00:00Z", "2026-10-17T11:50:00Z"))
+	s.Close()
+	f, err := os.OpenFile(filepath.Join(s.dir, "2026-10-17.jsonl"), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"observed_at":"2026-10-17T13:`) // the half line of a crash
+	f.Close()
+
+	found, err := s.Query("", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cities(found); !slices.Equal(got, []string{"Oslo"}) {
+		t.Errorf("got %v, want [Oslo]", got)
+	}
+}
+
+func TestCompact(t *testing.T) {
+	s := openStore(t,
+		// polled twice before the station measured again: the second one is a duplicate
+		observation("Oslo", "2026-09-29T10:00:00Z", "2026-09-29T09:50:00Z"),
+		observation("Oslo", "2026-09-29T10:05:00Z", "2026-09-29T09:50:00Z"),
+		observation("Oslo", "2026-09-30T10:00:00Z", "2026-09-30T09:50:00Z"),
+		// failed fetches have nothing to compare, both stay
+		observation("Paris", "2026-09-30T10:00:00Z", ""),
+		observation("Paris", "2026-09-30T10:05:00Z", ""),
+		observation("Oslo", "2026-10-01T10:00:00Z", "2026-10-01T09:50:00Z"),
+		observation("Oslo", "2026-10-18T10:00:00Z", "2026-10-18T09:50:00Z"),
+	)
+
+	stats, err := s.Compact(date("2026-10-18T12:00:00Z"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := CompactStats{Segments: 3, Months: 2, Kept: 5, Duplicates: 1}
+	if stats != want {
+		t.Errorf("stats %+v, want %+v", stats, want)
+	}
+	// today's segment is still being appended to
+	if got, want := segmentNames(t, s), []string{"2026-09", "2026-10", "2026-10-18"}; !slices.Equal(got, want) {
+		t.Errorf("segments %v, want %v", got, want)
+	}
+
+	// a later compaction merges into the month written before, duplicates included
+	err = s.Append(
+		observation("Oslo", "2026-10-01T10:05:00Z", "2026-10-01T09:50:00Z"),
+		observation("Oslo", "2026-10-02T10:00:00Z", "2026-10-02T09:50:00Z"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats, err = s.Compact(date("2026-10-18T12:00:00Z"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = CompactStats{Segments: 2, Months: 1, Kept: 2, Duplicates: 1}
+	if stats != want {
+		t.Errorf("second compaction %+v, want %+v", stats, want)
+	}
+
+	found, err := s.Query("", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var observed []string
+	for _, obs := range found {
+		observed = append(observed, obs.ObservedAt.Format("01-02T15:04"))
+	}
+	wantObserved := []string{"09-29T10:00", "09-30T10:00", "09-30T10:00", "09-30T10:05", "10-01T10:00", "10-02T10:00", "10-18T10:00"}
+	if !slices.Equal(observed, wantObserved) {
+		t.Errorf("stored %v, want %v", observed, wantObserved)
+	}
+}
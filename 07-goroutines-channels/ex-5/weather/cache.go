@@ -0,0 +1,164 @@
+package weather
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Cache keeps successful responses in memory for TTL and collapses concurrent
+// requests for the same city into a single upstream call (the "singleflight" pattern).
+// Errors are never cached. A Cache is safe for use by many goroutines and can be
+// shared between clients.
+//
+// Expired entries are dropped when they are looked up, or when the cache is full and
+// room is needed for a new one. Past MaxEntries the entry closest to expiring goes.
+type Cache struct {
+	TTL        time.Duration
+	MaxEntries int // 0 or less means no limit
+
+	mu       sync.Mutex
+	entries  map[string]cacheEntry
+	inflight map[string]*call
+
+	hits, misses, shared atomic.Int64
+}
+
+type cacheEntry struct {
+	data      WeatherResponse
+	expiresAt time.Time
+}
+
+// call is one upstream request that other goroutines can wait on.
+// done is closed once data, info and err are set.
+type call struct {
+	done    chan struct{}
+	cancel  context.CancelFunc
+	waiters int // callers still waiting for it, guarded by Cache.mu
+
+	data WeatherResponse
+	info fetchInfo
+	err  error
+}
+
+// CacheStats is a snapshot of the cache counters.
+type CacheStats struct {
+	Hits   int64 // served from memory
+	Misses int64 // went upstream
+	Shared int64 // waited for an identical request that was already in flight
+}
+
+// DefaultCacheEntries is the MaxEntries of NewCache, far more cities than a run asks for.
+const DefaultCacheEntries = 10000
+
+// NewCache returns an empty cache whose entries live for ttl.
+func NewCache(ttl time.Duration) *Cache {
+	return &Cache{
+		TTL:        ttl,
+		MaxEntries: DefaultCacheEntries,
+		entries:    make(map[string]cacheEntry),
+		inflight:   make(map[string]*call),
+	}
+}
+
+// Stats returns the current hit/miss counters.
+func (c *Cache) Stats() CacheStats {
+	return CacheStats{Hits: c.hits.Load(), Misses: c.misses.Load(), Shared: c.shared.Load()}
+}
+
+// cacheKey ignores the case of the city name, "london" and "London" are the same request.
+func cacheKey(city string, units Units) string {
+	return strings.ToLower(strings.TrimSpace(city)) + "|" + string(units)
+}
+
+// do returns the cached response for key or calls fetch to get it.
+// If another goroutine is already fetching the same key, do waits for that result
+// instead of starting a second request. cached reports whether fetch was skipped,
+// info is what fetch reported when it wasn't.
+//
+// The request is shared, so it doesn't run on the context of whoever happened to ask
+// first: fetch gets a context of its own, which is only cancelled once every caller
+// waiting for it has given up. A caller whose ctx is done stops waiting and gets
+// ctx.Err(), the others still get the answer.
+func (c *Cache) do(ctx context.Context, key string, fetch func(ctx context.Context) (WeatherResponse, fetchInfo, error)) (data WeatherResponse, info fetchInfo, cached bool, err error) {
+	c.mu.Lock()
+	if entry, ok := c.entries[key]; ok {
+		if time.Now().Before(entry.expiresAt) {
+			c.mu.Unlock()
+			c.hits.Add(1)
+			return entry.data, fetchInfo{}, true, nil
+		}
+		delete(c.entries, key)
+	}
+
+	cl, shared := c.inflight[key]
+	if shared {
+		cl.waiters++
+		c.shared.Add(1)
+	} else {
+		// we are the first one asking, everyone else waits on this call
+		fetchCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		cl = &call{done: make(chan struct{}), cancel: cancel, waiters: 1}
+		c.inflight[key] = cl
+		c.misses.Add(1)
+		go c.run(fetchCtx, key, cl, fetch)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-cl.done:
+		if shared {
+			return cl.data, fetchInfo{}, true, cl.err
+		}
+		return cl.data, cl.info, false, cl.err
+	case <-ctx.Done():
+		c.mu.Lock()
+		cl.waiters--
+		if cl.waiters == 0 {
+			// nobody wants the answer anymore, a later caller starts a new request
+			cl.cancel()
+			if c.inflight[key] == cl {
+				delete(c.inflight, key)
+			}
+		}
+		c.mu.Unlock()
+		return WeatherResponse{}, fetchInfo{}, false, ctx.Err() // gave up, nothing was served
+	}
+}
+
+// run performs the shared request of cl and stores a successful answer.
+func (c *Cache) run(ctx context.Context, key string, cl *call, fetch func(ctx context.Context) (WeatherResponse, fetchInfo, error)) {
+	defer cl.cancel()
+	cl.data, cl.info, cl.err = fetch(ctx)
+
+	c.mu.Lock()
+	if c.inflight[key] == cl {
+		delete(c.inflight, key)
+	}
+	if cl.err == nil && c.TTL > 0 {
+		c.store(key, cacheEntry{data: cl.data, expiresAt: time.Now().Add(c.TTL)})
+	}
+	c.mu.Unlock()
+	close(cl.done)
+}
+
+// store adds entry, making room first when the cache is full. c.mu must be held.
+func (c *Cache) store(key string, entry cacheEntry) {
+	if _, ok := c.entries[key]; !ok && c.MaxEntries > 0 && len(c.entries) >= c.MaxEntries {
+		now := time.Now()
+		var soonest string
+		for k, e := range c.entries {
+			if !now.Before(e.expiresAt) {
+				delete(c.entries, k)
+			} else if soonest == "" || e.expiresAt.Before(c.entries[soonest].expiresAt) {
+				soonest = k
+			}
+		}
+		if len(c.entries) >= c.MaxEntries {
+			delete(c.entries, soonest)
+		}
+	}
+	c.entries[key] = entry
+}
@@ -0,0 +1,126 @@
+package weather_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"golang-fast-start/07-goroutines-channels/ex-5/weather"
+	"golang-fast-start/07-goroutines-channels/ex-5/weather/fakeapi"
+)
+
+func TestCacheDuplicateCitiesShareOneRequest(t *testing.T) {
+	fake := fakeapi.New()
+	fake.Latency = 50 * time.Millisecond // long enough for the three to overlap
+	client := newFakeClient(t, fake)
+	client.Cache = weather.NewCache(time.Minute)
+
+	var cached int
+	for r := range client.FetchAll(context.Background(), []string{"London", "london", " London "}, weather.FanOutOptions{}) {
+		if r.Err != nil {
+			t.Fatalf("%v: %v", r.City, r.Err)
+		}
+		if r.Cached {
+			cached++
+		}
+	}
+	if hits := fake.Hits("London"); hits != 1 {
+		t.Errorf("server hits %d, want 1", hits)
+	}
+	if cached != 2 {
+		t.Errorf("%d results cached, want 2", cached)
+	}
+	if stats := client.Cache.Stats(); stats.Misses != 1 || stats.Hits+stats.Shared != 2 {
+		t.Errorf("stats %+v, want 1 miss and 2 hits or shared", stats)
+	}
+}
+
+func TestCacheEntryExpires(t *testing.T) {
+	fake := fakeapi.New()
+	client := newFakeClient(t, fake)
+	client.Cache = weather.NewCache(50 * time.Millisecond)
+
+	for range 2 {
+		if _, err := client.Current(context.Background(), "Paris"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if hits := fake.Hits("Paris"); hits != 1 {
+		t.Fatalf("server hits %d within the TTL, want 1", hits)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if _, err := client.Current(context.Background(), "Paris"); err != nil {
+		t.Fatal(err)
+	}
+	if hits := fake.Hits("Paris"); hits != 2 {
+		t.Errorf("server hits %d after the TTL, want 2", hits)
+	}
+}
+
+// startFetch fetches city in a goroutine, the result arrives on the channel.
+func startFetch(ctx context.Context, client *weather.Client, city string) <-chan weather.WeatherResult {
+	ch := make(chan weather.WeatherResult, 1)
+	go func() { ch <- fetchOne(ctx, client, city) }()
+	return ch
+}
+
+func TestCacheWaiterCancelling(t *testing.T) {
+	fake := fakeapi.New()
+	fake.Latency = 200 * time.Millisecond
+	client := newFakeClient(t, fake)
+	client.Cache = weather.NewCache(time.Minute)
+
+	// the first caller starts the request and hangs up while it runs
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+	leader := startFetch(leaderCtx, client, "Tokyo")
+	time.Sleep(20 * time.Millisecond)
+	followerCtx, cancelFollower := context.WithCancel(context.Background())
+	defer cancelFollower()
+	follower := startFetch(followerCtx, client, "Tokyo")
+	quitter := startFetch(leaderCtx, client, "Tokyo") // joins the same request, gives up with the leader
+	time.Sleep(20 * time.Millisecond)
+	cancelLeader()
+
+	for _, r := range []weather.WeatherResult{<-leader, <-quitter} {
+		if !errors.Is(r.Err, context.Canceled) {
+			t.Errorf("cancelled caller got %v, want context.Canceled", r.Err)
+		}
+		if r.Cached {
+			t.Error("a cancelled caller is reported as cached")
+		}
+	}
+
+	// the others still get the answer of the one request
+	if r := <-follower; r.Err != nil || r.Data.Name != "Tokyo" {
+		t.Errorf("follower got %q, %v after the leader gave up", r.Data.Name, r.Err)
+	}
+	if hits := fake.Hits("Tokyo"); hits != 1 {
+		t.Errorf("server hits %d, want 1", hits)
+	}
+}
+
+func TestCacheRequestCancelledWhenEveryoneGaveUp(t *testing.T) {
+	fake := fakeapi.New()
+	fake.Latency = 200 * time.Millisecond
+	client := newFakeClient(t, fake)
+	client.Cache = weather.NewCache(time.Minute)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	a, b := startFetch(ctx, client, "Oslo"), startFetch(ctx, client, "Oslo")
+	for _, r := range []weather.WeatherResult{<-a, <-b} {
+		if !r.TimedOut() {
+			t.Errorf("got %v, want a timeout", r.Err)
+		}
+	}
+
+	// nothing was cached and nothing is in flight anymore: the next caller asks again
+	if r := fetchOne(context.Background(), client, "Oslo"); r.Err != nil || r.Cached {
+		t.Errorf("next caller got cached=%v, %v", r.Cached, r.Err)
+	}
+	if hits := fake.Hits("Oslo"); hits != 2 {
+		t.Errorf("server hits %d, want 2", hits)
+	}
+}
@@ -0,0 +1,256 @@
+// Package weather is a small client for the OpenWeatherMap API.
+// It started life as the fetchWeather function of the ex-5 goroutines example and
+// was pulled out so the same code can be pointed at the real service in production
+// and at a local httptest server in tests.
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Get API Key from the https://openweathermap.org/ and locate in .env file
+// http://api.openweathermap.org/data/2.5/weather?q={CITY}&appid={API_KEY}
+
+// DefaultBaseURL is the root of the OpenWeatherMap data API. Endpoint paths like
+// "/weather" are appended to it.
+const DefaultBaseURL = "http://api.openweathermap.org/data/2.5"
+
+// DefaultGeoBaseURL is the root of the geocoding API.
+const DefaultGeoBaseURL = "http://api.openweathermap.org/geo/1.0"
+
+// Client talks to an OpenWeatherMap compatible server.
+// The zero value is not usable, create one with NewClient and override the fields as needed.
+type Client struct {
+	BaseURL    string       // e.g. DefaultBaseURL or the URL of an httptest.Server
+	GeoBaseURL string       // "" derives it from BaseURL, see geoBaseURL
+	APIKey     string       // sent as the appid query parameter
+	HTTPClient *http.Client // transport used for every request
+	Retry      RetryPolicy  // zero value disables retries
+	Units      Units        // "" behaves like Standard (Kelvin)
+	Cache      *Cache       // optional in-memory cache, nil sends every request upstream
+	DiskCache  *DiskCache   // optional on-disk cache consulted after Cache, before the network
+	Limiter    *Limiter     // optional rate limit shared by every request of this client
+	Debug      *log.Logger  // when set, every request is logged with the API key redacted
+	Metrics    *Metrics     // optional, collects request, cache and error metrics
+
+	// ResolveNames geocodes city names to coordinates before asking for the weather,
+	// so "Springfield,US" means the same place every time instead of whatever the
+	// weather endpoint picks. Coordinates and city IDs are used as they are.
+	ResolveNames bool
+
+	placesMu sync.Mutex
+	places   map[string]Query // lower-case city name -> Query with coordinates, filled by ResolveNames
+}
+
+// NewClient returns a Client for the real OpenWeatherMap service.
+func NewClient(apiKey string) *Client {
+	return &Client{
+		BaseURL:    DefaultBaseURL,
+		APIKey:     apiKey,
+		HTTPClient: http.DefaultClient,
+		Retry:      DefaultRetryPolicy,
+		Units:      Standard,
+	}
+}
+
+// Current fetches the current weather for a single city.
+// city is anything ParseQuery understands: "London", "London,GB", "id:2643743" or "51.51,-0.13".
+// The request is bound to ctx, so cancelling ctx aborts an in-flight call and any pending retry.
+func (c *Client) Current(ctx context.Context, city string) (WeatherResponse, error) {
+	data, _, err := c.current(ctx, city)
+	return data, err
+}
+
+// fetchInfo is what the fan-out reports about a request besides the data itself.
+type fetchInfo struct {
+	attempts int           // requests sent upstream by every provider asked, 0 when the answer came from the cache
+	cached   bool          // served by a cache or by an identical in-flight request
+	wait     time.Duration // time spent waiting for the rate limiter, summed over all attempts
+
+	// A Fallback that moves on to the next provider didn't retry anything. fallbacks
+	// counts the providers that failed before the last one asked, retried is set when
+	// one of them repeated a request.
+	fallbacks int
+	retried   bool
+}
+
+// didRetry reports whether some provider sent a request more than once. A single
+// provider did when it made more than one attempt.
+func (i fetchInfo) didRetry() bool {
+	return i.retried || (i.fallbacks == 0 && i.attempts > 1)
+}
+
+// current is Current plus the details the fan-out reports.
+func (c *Client) current(ctx context.Context, city string) (data WeatherResponse, info fetchInfo, err error) {
+	defer func() { c.Metrics.observeError(err) }()
+
+	// a city cancelled before its turn (FailFast) is neither a cache hit nor a miss
+	if err := ctx.Err(); err != nil {
+		return WeatherResponse{}, fetchInfo{}, err
+	}
+	if c.Cache == nil {
+		return c.currentUpstream(ctx, city)
+	}
+
+	data, info, cached, err := c.Cache.do(ctx, cacheKey(city, c.Units), func(ctx context.Context) (WeatherResponse, fetchInfo, error) {
+		return c.currentUpstream(ctx, city)
+	})
+	info.cached = info.cached || cached // the disk cache may have answered a memory miss
+	c.Metrics.observeCache("memory", cached)
+	return data, info, err
+}
+
+// currentUpstream goes to the disk cache if there is one, and to the API otherwise.
+func (c *Client) currentUpstream(ctx context.Context, city string) (WeatherResponse, fetchInfo, error) {
+	var data WeatherResponse
+	var info fetchInfo
+	var err error
+
+	if c.DiskCache == nil {
+		data, _, info, err = c.currentHTTP(ctx, city, validators{})
+	} else {
+		data, info.cached, err = c.DiskCache.do(city, c.Units, c.debugf, func(prev validators) (WeatherResponse, validators, error) {
+			data, fresh, upstream, err := c.currentHTTP(ctx, city, prev)
+			info = upstream
+			return data, fresh, err
+		})
+		c.Metrics.observeCache("disk", info.cached)
+	}
+
+	data.setTempUnit(c.Units.TempUnit())
+	if err == nil {
+		data.Source = c.Name()
+	}
+	return data, info, err
+}
+
+// CurrentAt fetches the current weather at the given coordinates.
+func (c *Client) CurrentAt(ctx context.Context, lat, lon float64) (WeatherResponse, error) {
+	return c.Current(ctx, Query{Coord: &Coord{Lat: lat, Lon: lon}}.String())
+}
+
+// CurrentByID fetches the current weather for an OpenWeatherMap city ID, the one
+// unambiguous way to name a city (see WeatherResponse.ID).
+func (c *Client) CurrentByID(ctx context.Context, id int) (WeatherResponse, error) {
+	return c.Current(ctx, Query{ID: id}.String())
+}
+
+// currentHTTP asks the API for the current weather, see fetchJSON.
+func (c *Client) currentHTTP(ctx context.Context, city string, prev validators) (WeatherResponse, validators, fetchInfo, error) {
+	var data WeatherResponse
+	query, geo, err := c.queryValues(ctx, city)
+	if err != nil {
+		return data, validators{}, geo, err
+	}
+
+	fresh, info, err := c.fetchJSON(ctx, c.BaseURL+"/weather", query, prev, &data)
+	info.wait += geo.wait // Attempts only counts the weather request, the limiter counts both
+	return data, fresh, info, err
+}
+
+// fetchJSON sends a GET request for endpoint through the rate limiter, retrying transient
+// failures, and decodes the JSON body into v. v is only written by a successful attempt,
+// a failed decode is not transient and ends the retries.
+// prev makes the request conditional, the returned validators belong to the new response.
+func (c *Client) fetchJSON(ctx context.Context, endpoint string, query url.Values, prev validators, v any) (validators, fetchInfo, error) {
+	var fresh validators
+	var info fetchInfo
+	var err error
+	info.attempts, err = c.Retry.do(ctx, func() error {
+		// retries go through the limiter too, otherwise they would eat the budget of other cities
+		if err := c.wait(ctx, &info); err != nil {
+			return err
+		}
+		var err error
+		fresh, err = c.getConditional(ctx, endpoint, query, prev, v)
+		return err
+	})
+	return fresh, info, err
+}
+
+// wait blocks on the rate limiter, if any, and adds the time spent to info.
+func (c *Client) wait(ctx context.Context, info *fetchInfo) error {
+	if c.Limiter == nil {
+		return nil
+	}
+	waited, err := c.Limiter.Wait(ctx)
+	info.wait += waited
+	c.Metrics.observeRateWait(waited)
+	return err
+}
+
+// getConditional performs a single GET request against endpoint and decodes the JSON body
+// into v. If-None-Match / If-Modified-Since are taken from prev, a 304 answer returns
+// errNotModified. The validators of the response are returned otherwise.
+// query is encoded with url.Values, so names like "São Paulo" or "New York" arrive intact.
+// Every error it returns has the API key redacted.
+func (c *Client) getConditional(ctx context.Context, endpoint string, query url.Values, prev validators, v any) (_ validators, err error) {
+	defer func() { err = c.redact(err) }()
+
+	query.Set("appid", c.APIKey)
+	if c.Units != "" {
+		query.Set("units", string(c.Units))
+	}
+
+	rawURL := endpoint + "?" + query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return validators{}, err
+	}
+	if prev.etag != "" {
+		req.Header.Set("If-None-Match", prev.etag)
+	}
+	if prev.lastModified != "" {
+		req.Header.Set("If-Modified-Since", prev.lastModified)
+	}
+
+	start := time.Now()
+	resp, err := c.httpClient().Do(req)
+	endpointName := path.Base(endpoint) // "weather", "forecast" or "direct"
+	if err != nil {
+		c.debugf("GET %v failed after %v: %v", c.redactURL(rawURL), time.Since(start), c.redact(err))
+		c.Metrics.observeRequest(endpointName, "error", time.Since(start))
+		return validators{}, err
+	}
+	c.debugf("GET %v -> %v in %v", c.redactURL(rawURL), resp.Status, time.Since(start))
+	c.Metrics.observeRequest(endpointName, strconv.Itoa(resp.StatusCode), time.Since(start))
+
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return validators{}, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified {
+		return validators{}, errNotModified
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return validators{}, newAPIError(resp, body)
+	}
+
+	fresh := validators{etag: resp.Header.Get("ETag"), lastModified: resp.Header.Get("Last-Modified")}
+	return fresh, json.Unmarshal(body, v)
+}
+
+func (c *Client) debugf(format string, args ...any) {
+	if c.Debug != nil {
+		c.Debug.Printf(format, args...)
+	}
+}
+
+// httpClient falls back to http.DefaultClient so a Client built by hand still works.
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient == nil {
+		return http.DefaultClient
+	}
+	return c.HTTPClient
+}
@@ -0,0 +1,183 @@
+package weather
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DiskCache stores one JSON file per city in Dir so responses survive across runs.
+// Entries younger than TTL are served without touching the network. Older entries are
+// revalidated with If-None-Match / If-Modified-Since when the server sent an ETag or
+// Last-Modified header, and a 304 answer reuses the stored data.
+//
+// Goroutines of the same process take a per-file lock, and files are written to a
+// temporary name first and then renamed, so a concurrent reader (even another process)
+// never sees a half written entry.
+type DiskCache struct {
+	Dir string
+	TTL time.Duration
+
+	locks sync.Map // file path -> *sync.Mutex
+}
+
+// DiskEntry is the content of one cache file.
+type DiskEntry struct {
+	City         string          `json:"city"`
+	Units        Units           `json:"units"`
+	FetchedAt    time.Time       `json:"fetched_at"`
+	ETag         string          `json:"etag,omitempty"`
+	LastModified string          `json:"last_modified,omitempty"`
+	Data         WeatherResponse `json:"data"`
+}
+
+// validators are the response headers that make a conditional request possible.
+type validators struct {
+	etag         string
+	lastModified string
+}
+
+// errNotModified is returned by the client when a conditional request got a 304.
+var errNotModified = errors.New("not modified")
+
+// NewDiskCache creates dir if needed and returns a cache whose entries are fresh for ttl.
+// A directory that can't be written is an error right away: the cache would miss on
+// every run and spend the API quota it is there to save.
+func NewDiskCache(dir string, ttl time.Duration) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	probe, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return nil, fmt.Errorf("cache directory is not writable: %w", err)
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+	return &DiskCache{Dir: dir, TTL: ttl}, nil
+}
+
+// path maps a city to its file, escaping the name so "São Paulo" or "a/b" are safe file names.
+func (d *DiskCache) path(city string, units Units) string {
+	name := url.QueryEscape(strings.ToLower(strings.TrimSpace(city)))
+	if units == "" {
+		units = Standard
+	}
+	return filepath.Join(d.Dir, name+"."+string(units)+".json")
+}
+
+func (d *DiskCache) lock(path string) func() {
+	mu, _ := d.locks.LoadOrStore(path, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	return mu.(*sync.Mutex).Unlock
+}
+
+// do serves the city from disk when possible, otherwise calls fetch with the validators of
+// the stale entry (if any) and stores the new response. cached reports whether the data
+// came from disk, including revalidated entries.
+// An entry that can't be written doesn't fail the request, the data is valid either way,
+// but it is reported to logf.
+func (d *DiskCache) do(city string, units Units, logf func(format string, args ...any), fetch func(prev validators) (WeatherResponse, validators, error)) (data WeatherResponse, cached bool, err error) {
+	path := d.path(city, units)
+	unlock := d.lock(path)
+	defer unlock()
+
+	entry, _ := readDiskEntry(path) // a missing or corrupt file is just a miss
+	if entry != nil && time.Since(entry.FetchedAt) < d.TTL {
+		return entry.Data, true, nil
+	}
+
+	var prev validators
+	if entry != nil {
+		prev = validators{etag: entry.ETag, lastModified: entry.LastModified}
+	}
+
+	data, fresh, err := fetch(prev)
+	if errors.Is(err, errNotModified) && entry != nil {
+		entry.FetchedAt = time.Now()
+		if err := writeDiskEntry(path, entry); err != nil {
+			logf("disk cache: %v", err)
+		}
+		return entry.Data, true, nil
+	}
+	if err != nil {
+		return data, false, err
+	}
+
+	err = writeDiskEntry(path, &DiskEntry{
+		City:         city,
+		Units:        units,
+		FetchedAt:    time.Now(),
+		ETag:         fresh.etag,
+		LastModified: fresh.lastModified,
+		Data:         data,
+	})
+	if err != nil {
+		logf("disk cache: %v", err)
+	}
+	return data, false, nil
+}
+
+// Purge deletes every entry fetched more than maxAge ago, plus files that can't be read
+// as an entry, and returns how many files were removed.
+func (d *DiskCache) Purge(maxAge time.Duration) (int, error) {
+	paths, err := filepath.Glob(filepath.Join(d.Dir, "*.json"))
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, path := range paths {
+		unlock := d.lock(path)
+		entry, err := readDiskEntry(path)
+		if err != nil || time.Since(entry.FetchedAt) > maxAge {
+			if err := os.Remove(path); err == nil || errors.Is(err, fs.ErrNotExist) {
+				removed++
+			}
+		}
+		unlock()
+	}
+	return removed, nil
+}
+
+func readDiskEntry(path string) (*DiskEntry, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entry DiskEntry
+	if err := json.Unmarshal(b, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// writeDiskEntry writes to a temporary file in the same directory and renames it,
+// rename is atomic so readers see either the old or the new entry.
+func writeDiskEntry(path string, entry *DiskEntry) error {
+	b, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op after a successful rename
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
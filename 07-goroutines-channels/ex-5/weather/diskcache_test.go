@@ -0,0 +1,118 @@
+package weather_test
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang-fast-start/07-goroutines-channels/ex-5/weather"
+	"golang-fast-start/07-goroutines-channels/ex-5/weather/fakeapi"
+)
+
+func newDiskClient(t *testing.T, fake *fakeapi.Server, ttl time.Duration) *weather.Client {
+	t.Helper()
+	client := newFakeClient(t, fake)
+	var err error
+	client.DiskCache, err = weather.NewDiskCache(t.TempDir(), ttl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestDiskCacheFreshEntry(t *testing.T) {
+	fake := fakeapi.New()
+	client := newDiskClient(t, fake, time.Minute)
+
+	first := fetchOne(context.Background(), client, "London")
+	second := fetchOne(context.Background(), client, "London")
+	if first.Err != nil || second.Err != nil {
+		t.Fatal(first.Err, second.Err)
+	}
+	if first.Cached || !second.Cached || second.Attempts != 0 {
+		t.Errorf("cached %v then %v (%d attempts), want false then true without a request", first.Cached, second.Cached, second.Attempts)
+	}
+	if hits := fake.Hits("London"); hits != 1 {
+		t.Errorf("server hits %d, want 1", hits)
+	}
+}
+
+func TestDiskCacheRevalidates(t *testing.T) {
+	fake := fakeapi.New()
+	client := newDiskClient(t, fake, 0) // every entry is stale, so every lookup revalidates
+
+	first := fetchOne(context.Background(), client, "Paris")
+	second := fetchOne(context.Background(), client, "Paris")
+	if first.Err != nil || second.Err != nil {
+		t.Fatal(first.Err, second.Err)
+	}
+
+	// the second request was sent with If-None-Match and got a 304
+	if hits := fake.Hits("Paris"); hits != 2 {
+		t.Errorf("server hits %d, want 2", hits)
+	}
+	if !second.Cached || second.Attempts != 1 {
+		t.Errorf("revalidated answer: cached %v, %d attempts, want true and 1", second.Cached, second.Attempts)
+	}
+	if second.Data.Name != "Paris" || second.Data.Main.Temp != first.Data.Main.Temp {
+		t.Errorf("revalidated data %+v differs from %+v", second.Data.Main, first.Data.Main)
+	}
+}
+
+func TestDiskCachePurge(t *testing.T) {
+	fake := fakeapi.New()
+	client := newDiskClient(t, fake, time.Minute)
+	dir := client.DiskCache.Dir
+
+	fetchOne(context.Background(), client, "London")
+	time.Sleep(50 * time.Millisecond)
+	fetchOne(context.Background(), client, "Tokyo")
+	if err := os.WriteFile(filepath.Join(dir, "broken.metric.json"), []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := client.DiskCache.Purge(25 * time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 2 {
+		t.Errorf("removed %d files, want London and the broken one", removed)
+	}
+	left, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(left) != 1 || !strings.HasPrefix(filepath.Base(left[0]), "tokyo.") {
+		t.Errorf("left %v, want only the Tokyo entry", left)
+	}
+}
+
+func TestDiskCacheWriteErrorIsLogged(t *testing.T) {
+	fake := fakeapi.New()
+	client := newDiskClient(t, fake, time.Minute)
+	var buf bytes.Buffer
+	client.Debug = log.New(&buf, "", 0)
+
+	// the directory disappears after the cache was set up
+	if err := os.RemoveAll(client.DiskCache.Dir); err != nil {
+		t.Fatal(err)
+	}
+	if r := fetchOne(context.Background(), client, "Oslo"); r.Err != nil {
+		t.Fatalf("a cache that can't be written failed the request: %v", r.Err)
+	}
+	if !strings.Contains(buf.String(), "disk cache:") {
+		t.Errorf("the write error was not logged:\n%v", buf.String())
+	}
+}
+
+func TestNewDiskCacheRejectsBadDir(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := weather.NewDiskCache(filepath.Join(file, "cache"), time.Minute); err == nil {
+		t.Error("a cache directory below a file was accepted")
+	}
+}
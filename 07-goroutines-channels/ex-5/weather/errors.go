@@ -0,0 +1,125 @@
+package weather
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Sentinel errors for the failures callers usually want to handle differently.
+// They are matched with errors.Is against the *APIError returned by the client:
+//
+//	if errors.Is(err, weather.ErrCityNotFound) { ... skip the city ... }
+var (
+	ErrUnauthorized = errors.New("invalid or missing API key")
+	ErrCityNotFound = errors.New("city not found")
+	ErrRateLimited  = errors.New("rate limited")
+	ErrServer       = errors.New("weather service unavailable")
+)
+
+// APIError is returned when the server answers with a non-2xx status code.
+// Code and Message come from the error payload OpenWeatherMap sends along, e.g.
+// {"cod":"404","message":"city not found"}, and are empty when the body was not JSON.
+type APIError struct {
+	StatusCode int
+	Code       string        // the "cod" field, OpenWeatherMap sends it as a string or a number
+	Message    string        // the "message" field
+	RetryAfter time.Duration // parsed from the Retry-After header, 0 when the header is missing
+}
+
+func (e *APIError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("weather api: %d %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("weather api: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// Is makes errors.Is(err, ErrCityNotFound) and friends work on an *APIError.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrCityNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrServer:
+		return e.StatusCode >= 500
+	}
+	return false
+}
+
+// newAPIError builds an *APIError from a failed response and its already read body.
+// A body that is not the usual error payload is not an error by itself, the status code
+// still says what went wrong.
+func newAPIError(resp *http.Response, body []byte) *APIError {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+
+	var payload struct {
+		Cod     json.RawMessage `json:"cod"`
+		Message string          `json:"message"`
+	}
+	if json.Unmarshal(body, &payload) == nil {
+		apiErr.Code = string(bytes.Trim(payload.Cod, `"`))
+		apiErr.Message = payload.Message
+	}
+	return apiErr
+}
+
+// ErrorCategory sorts an error into a small fixed set of names, for metrics and reports:
+// unauthorized, not_found, rate_limited, server, http_<code>, timeout, canceled, network,
+// decode or other.
+func ErrorCategory(err error) string {
+	var apiErr *APIError
+	var netErr net.Error
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case err == nil:
+		return ""
+	// before the API errors: a retry cut off by the deadline carries the last one too
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, ErrUnauthorized):
+		return "unauthorized"
+	case errors.Is(err, ErrCityNotFound):
+		return "not_found"
+	case errors.Is(err, ErrRateLimited):
+		return "rate_limited"
+	case errors.Is(err, ErrServer):
+		return "server"
+	case errors.As(err, &apiErr):
+		return "http_" + strconv.Itoa(apiErr.StatusCode)
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+		return "decode"
+	case errors.As(err, &netErr):
+		return "network"
+	}
+	return "other"
+}
+
+// parseRetryAfter understands both forms of the header: delay in seconds or an HTTP date.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0)
+	}
+	return 0
+}
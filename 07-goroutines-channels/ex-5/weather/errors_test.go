@@ -0,0 +1,65 @@
+package weather_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"golang-fast-start/07-goroutines-channels/ex-5/weather"
+	"golang-fast-start/07-goroutines-channels/ex-5/weather/fakeapi"
+)
+
+func TestAPIErrors(t *testing.T) {
+	fake := fakeapi.New()
+	fake.SetFault("Oslo", fakeapi.Fault{Status: http.StatusTooManyRequests, RetryAfter: "7"})
+
+	tests := []struct {
+		name       string
+		key        string
+		city       string
+		status     int
+		sentinel   error
+		category   string
+		retryAfter time.Duration
+	}{
+		{"bad key", "wrong-key", "London", http.StatusUnauthorized, weather.ErrUnauthorized, "unauthorized", 0},
+		{"unknown city", "right-key", "Nowhere", http.StatusNotFound, weather.ErrCityNotFound, "not_found", 0},
+		{"rate limited", "right-key", "Oslo", http.StatusTooManyRequests, weather.ErrRateLimited, "rate_limited", 7 * time.Second},
+	}
+	fake.APIKey = "right-key"
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeClient(t, fake)
+			client.APIKey = tt.key
+			client.Retry = weather.RetryPolicy{} // a single attempt, Oslo would wait 7s otherwise
+
+			_, err := client.Current(context.Background(), tt.city)
+			if !errors.Is(err, tt.sentinel) {
+				t.Errorf("errors.Is(%v, %v) = false", err, tt.sentinel)
+			}
+			var apiErr *weather.APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("errors.As(*APIError) failed on %T: %v", err, err)
+			}
+			if apiErr.StatusCode != tt.status || apiErr.RetryAfter != tt.retryAfter {
+				t.Errorf("status %d, Retry-After %v, want %d and %v", apiErr.StatusCode, apiErr.RetryAfter, tt.status, tt.retryAfter)
+			}
+			if apiErr.Message == "" {
+				t.Error("the message of the error payload was not decoded")
+			}
+			if got := weather.ErrorCategory(err); got != tt.category {
+				t.Errorf("category %q, want %q", got, tt.category)
+			}
+
+			// only the matching sentinel
+			for _, other := range []error{weather.ErrUnauthorized, weather.ErrCityNotFound, weather.ErrRateLimited, weather.ErrServer} {
+				if other != tt.sentinel && errors.Is(err, other) {
+					t.Errorf("errors.Is(%v, %v) = true", err, other)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package fakeapi
+
+import (
+	"time"
+
+	"golang-fast-start/07-goroutines-channels/ex-5/weather"
+)
+
+// DefaultCities returns the canned responses New starts with: the ex-5 default city list
+// plus two names that only work when the query string is encoded properly.
+func DefaultCities() []weather.WeatherResponse {
+	return []weather.WeatherResponse{
+		newCity(6167865, "Toronto", "CA", 43.7001, -79.4163, 281.15, 76),
+		newCity(2643743, "London", "GB", 51.5085, -0.1257, 285.35, 82),
+		newCity(2988507, "Paris", "FR", 48.8534, 2.3488, 287.05, 71),
+		newCity(1850147, "Tokyo", "JP", 35.6895, 139.6917, 292.45, 64),
+		newCity(745044, "Istanbul", "TR", 41.0138, 28.9497, 291.85, 68),
+		newCity(524901, "Moscow", "RU", 55.7522, 37.6156, 274.65, 88),
+		newCity(3143244, "Oslo", "NO", 59.9127, 10.7461, 276.25, 79),
+		newCity(323786, "Ankara", "TR", 39.9199, 32.8543, 289.15, 45),
+		newCity(3448439, "São Paulo", "BR", -23.5475, -46.6361, 297.55, 73),
+		newCity(5128581, "New York", "US", 40.7143, -74.006, 284.95, 58),
+	}
+}
+
+// newCity fills in a plausible full response around a temperature in Kelvin.
+func newCity(id int, name, country string, lat, lon, kelvin float64, humidity int) weather.WeatherResponse {
+	temp := func(offset float64) weather.Temperature {
+		return weather.Temperature{Value: kelvin + offset, Unit: weather.Kelvin}
+	}
+	now := time.Now().Truncate(time.Hour)
+
+	return weather.WeatherResponse{
+		ID:    id,
+		Name:  name,
+		Coord: weather.Coord{Lat: lat, Lon: lon},
+		Conditions: []weather.Condition{
+			{ID: 803, Main: "Clouds", Description: "broken clouds", Icon: "04d"},
+		},
+		Main: weather.Main{
+			Temp:      temp(0),
+			FeelsLike: temp(-1.2),
+			TempMin:   temp(-2),
+			TempMax:   temp(1.5),
+			Pressure:  1013,
+			Humidity:  humidity,
+		},
+		Visibility: 10000,
+		Wind:       weather.Wind{Speed: 4.1, Deg: 240},
+		Clouds:     weather.Clouds{All: 75},
+		Sys: weather.Sys{
+			Country: country,
+			Sunrise: weather.UnixTime{Time: now.Add(-6 * time.Hour)},
+			Sunset:  weather.UnixTime{Time: now.Add(6 * time.Hour)},
+		},
+		Time: weather.UnixTime{Time: now},
+	}
+}
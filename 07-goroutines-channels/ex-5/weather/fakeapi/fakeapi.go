@@ -0,0 +1,318 @@
+// Package fakeapi is a stand-in for the OpenWeatherMap API that runs locally, and for
+// wttr.in's j1 format on /{city}?format=j1.
+// It serves canned per-city responses and can inject latency, error codes and broken
+// bodies, so the client, retries and the fan-out can be exercised without an API key
+// or network access:
+//
+//	srv := httptest.NewServer(fakeapi.New())
+//	defer srv.Close()
+//
+//	client := weather.NewClient("any-key")
+//	client.BaseURL = srv.URL
+package fakeapi
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang-fast-start/07-goroutines-channels/ex-5/weather"
+)
+
+// Fault changes how the server answers for one city.
+type Fault struct {
+	Latency    time.Duration // added before answering, on top of Server.Latency
+	Status     int           // answer with this status and an OpenWeatherMap error payload
+	Failures   int           // only the first Failures requests get Status, 0 means all of them
+	RetryAfter string        // Retry-After header sent with Status
+	Malformed  bool          // answer 200 with a truncated JSON body
+}
+
+// Server is an http.Handler that speaks enough of the OpenWeatherMap API for the weather package.
+// Temperatures are stored in Kelvin and converted according to the units query parameter.
+type Server struct {
+	APIKey     string        // when set, requests with a different appid get a 401
+	Latency    time.Duration // applied to every request
+	Synthesize bool          // invent data for unknown cities instead of answering 404
+
+	mu     sync.Mutex
+	cities map[string]weather.WeatherResponse // lowercase name -> response in Kelvin
+	faults map[string]Fault
+	hits   map[string]int
+}
+
+// New returns a server that knows the DefaultCities.
+func New() *Server {
+	s := &Server{
+		cities: make(map[string]weather.WeatherResponse),
+		faults: make(map[string]Fault),
+		hits:   make(map[string]int),
+	}
+	for _, data := range DefaultCities() {
+		s.SetCity(data)
+	}
+	return s
+}
+
+// SetCity adds or replaces the canned response for data.Name. Temperatures must be in Kelvin.
+func (s *Server) SetCity(data weather.WeatherResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cities[strings.ToLower(data.Name)] = data
+}
+
+// SetFault makes city misbehave, a zero Fault goes back to normal.
+func (s *Server) SetFault(city string, f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults[strings.ToLower(city)] = f
+}
+
+// Hits returns how many requests were made for city, handy to check caching and retries.
+func (s *Server) Hits(city string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hits[strings.ToLower(city)]
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("format") == "j1" {
+		s.serveWttr(w, r)
+		return
+	}
+	if s.APIKey != "" && query.Get("appid") != s.APIKey {
+		writeError(w, http.StatusUnauthorized, "Invalid API key. Please see https://openweathermap.org/faq#error401 for more info.")
+		return
+	}
+
+	// match on the last path segment so any prefix works: /weather, /data/2.5/weather, ...
+	units := weather.Units(query.Get("units"))
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/weather"):
+		if data, ok := s.lookup(w, r, query); ok {
+			writeJSON(w, r, inUnits(data, units))
+		}
+	case strings.HasSuffix(r.URL.Path, "/forecast"):
+		if data, ok := s.lookup(w, r, query); ok {
+			writeJSON(w, r, forecastFor(data, units))
+		}
+	case strings.HasSuffix(r.URL.Path, "/direct"):
+		s.serveGeocode(w, r, query)
+	default:
+		writeError(w, http.StatusNotFound, "Internal error: 404")
+	}
+}
+
+// lookup counts the request, applies the city's fault and finds its data.
+// ok is false when an answer was already written.
+func (s *Server) lookup(w http.ResponseWriter, r *http.Request, query url.Values) (data weather.WeatherResponse, ok bool) {
+	s.mu.Lock()
+	key, data, ok := s.find(query)
+	s.hits[key]++
+	hits := s.hits[key]
+	fault := s.faults[key]
+	s.mu.Unlock()
+
+	if !s.sleep(r, s.Latency+fault.Latency) {
+		return data, false // the client gave up, nobody is listening anymore
+	}
+
+	if fault.Status != 0 && (fault.Failures == 0 || hits <= fault.Failures) {
+		if fault.RetryAfter != "" {
+			w.Header().Set("Retry-After", fault.RetryAfter)
+		}
+		writeError(w, fault.Status, strings.ToLower(http.StatusText(fault.Status)))
+		return data, false
+	}
+	if fault.Malformed {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"name":%q,"main":{"temp":`, key)
+		return data, false
+	}
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "city not found")
+	}
+	return data, ok
+}
+
+// find resolves the q, id or lat/lon parameters to a city, s.mu must be held.
+// key is the lowercase city name that faults and hits are tracked under.
+func (s *Server) find(query url.Values) (key string, data weather.WeatherResponse, ok bool) {
+	if id := query.Get("id"); id != "" {
+		for key, data := range s.cities {
+			if strconv.Itoa(data.ID) == id {
+				return key, data, true
+			}
+		}
+		return "id:" + id, data, false
+	}
+
+	if query.Has("lat") || query.Has("lon") {
+		lat, latErr := strconv.ParseFloat(query.Get("lat"), 64)
+		lon, lonErr := strconv.ParseFloat(query.Get("lon"), 64)
+		if latErr != nil || lonErr != nil {
+			return "", data, false
+		}
+		// like the real API any point on earth has weather: the nearest known city
+		// within a degree, or made up data for the spot
+		best := 1.0
+		for name, city := range s.cities {
+			if d := math.Hypot(city.Coord.Lat-lat, city.Coord.Lon-lon); d < best {
+				key, data, ok, best = name, city, true, d
+			}
+		}
+		if !ok {
+			key = query.Get("lat") + "," + query.Get("lon")
+			data, ok = synthesize(key), true
+			data.Name, data.Coord = "", weather.Coord{Lat: lat, Lon: lon}
+		}
+		return key, data, ok
+	}
+
+	// "London,GB": the optional country code has to match
+	name, country, _ := strings.Cut(query.Get("q"), ",")
+	key = strings.ToLower(strings.TrimSpace(name))
+	data, ok = s.cities[key]
+	if !ok && s.Synthesize && key != "" {
+		data, ok = synthesize(strings.TrimSpace(name)), true
+	}
+	if ok && country != "" && !strings.EqualFold(countryCode(country), data.Sys.Country) && !s.Synthesize {
+		return key, weather.WeatherResponse{}, false
+	}
+	return key, data, ok
+}
+
+// countryCode takes the last part of "IL,US" or "US".
+func countryCode(s string) string {
+	parts := strings.Split(s, ",")
+	return strings.TrimSpace(parts[len(parts)-1])
+}
+
+// serveGeocode answers /geo/1.0/direct with the known cities matching q.
+func (s *Server) serveGeocode(w http.ResponseWriter, r *http.Request, query url.Values) {
+	s.mu.Lock()
+	key, data, ok := s.find(url.Values{"q": {query.Get("q")}})
+	s.hits[key]++
+	s.mu.Unlock()
+
+	places := []weather.Place{} // the real API answers [] when nothing matches
+	if ok {
+		places = append(places, weather.Place{
+			Name:    data.Name,
+			Lat:     data.Coord.Lat,
+			Lon:     data.Coord.Lon,
+			Country: data.Sys.Country,
+		})
+	}
+	writeJSON(w, r, places)
+}
+
+// forecastFor makes up 40 steps of 3 hours around the current temperature, warmer in the
+// afternoon and colder at night.
+func forecastFor(data weather.WeatherResponse, units weather.Units) weather.Forecast {
+	forecast := weather.Forecast{
+		City: weather.ForecastCity{
+			ID:       data.ID,
+			Name:     data.Name,
+			Coord:    data.Coord,
+			Country:  data.Sys.Country,
+			Timezone: data.Timezone,
+			Sunrise:  data.Sys.Sunrise,
+			Sunset:   data.Sys.Sunset,
+		},
+		Count: 40,
+	}
+
+	start := time.Now().Truncate(3 * time.Hour).Add(3 * time.Hour)
+	for i := range forecast.Count {
+		at := start.Add(time.Duration(i) * 3 * time.Hour)
+		step := data
+		offset := 4 * math.Sin(float64(at.UTC().Hour()-9)/24*2*math.Pi) // peaks at 15:00 UTC
+		step.Main.Temp.Value += offset
+		step.Main.FeelsLike.Value += offset
+		step = inUnits(step, units)
+
+		forecast.Entries = append(forecast.Entries, weather.ForecastEntry{
+			Time:       weather.UnixTime{Time: at},
+			Main:       step.Main,
+			Conditions: step.Conditions,
+			Clouds:     step.Clouds,
+			Wind:       step.Wind,
+			Visibility: step.Visibility,
+		})
+	}
+	return forecast
+}
+
+// sleep waits for d unless the request is cancelled first.
+func (s *Server) sleep(r *http.Request, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+	select {
+	case <-time.After(d):
+		return true
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+// inUnits converts the Kelvin temperatures (and the wind speed for imperial) like the real API does.
+func inUnits(data weather.WeatherResponse, units weather.Units) weather.WeatherResponse {
+	unit := units.TempUnit()
+	data.Main.Temp = data.Main.Temp.In(unit)
+	data.Main.FeelsLike = data.Main.FeelsLike.In(unit)
+	data.Main.TempMin = data.Main.TempMin.In(unit)
+	data.Main.TempMax = data.Main.TempMax.In(unit)
+	if units == weather.Imperial {
+		data.Wind.Speed *= 2.23694 // m/s to mph
+		data.Wind.Gust *= 2.23694
+	}
+	return data
+}
+
+// writeJSON sends v with an ETag and answers 304 when the client already has that version.
+func writeJSON(w http.ResponseWriter, r *http.Request, v any) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	sum := sha1.Sum(body)
+	etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
+
+// writeError sends the error payload OpenWeatherMap uses, e.g. {"cod":"404","message":"city not found"}.
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"cod": strconv.Itoa(status), "message": message})
+}
+
+// synthesize makes up stable data for any city name, the same name always gets the same weather.
+func synthesize(city string) weather.WeatherResponse {
+	h := fnv.New32a()
+	h.Write([]byte(strings.ToLower(city)))
+	seed := h.Sum32()
+	return newCity(int(seed%1_000_000), city, "ZZ", float64(seed%180)-90, float64(seed%360)-180, 253.15+float64(seed%50), int(seed%100))
+}
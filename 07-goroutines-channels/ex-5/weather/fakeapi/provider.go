@@ -0,0 +1,58 @@
+package fakeapi
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang-fast-start/07-goroutines-channels/ex-5/weather"
+)
+
+// Provider is a weather.Provider that answers from memory, without HTTP: the
+// DefaultCities, in Units like the Server would. Set Err to make every call fail,
+// e.g. to see a weather.Fallback move on to its next provider.
+type Provider struct {
+	Latency time.Duration
+	Err     error
+	Units   weather.Units // "" answers in Kelvin and m/s, like weather.Standard
+
+	mu     sync.Mutex
+	cities map[string]weather.WeatherResponse
+}
+
+// NewProvider returns a provider that knows the DefaultCities.
+func NewProvider() *Provider {
+	p := &Provider{cities: make(map[string]weather.WeatherResponse)}
+	for _, data := range DefaultCities() {
+		p.cities[strings.ToLower(data.Name)] = data
+	}
+	return p
+}
+
+func (p *Provider) Name() string { return "fake" }
+
+func (p *Provider) Current(ctx context.Context, city string) (weather.WeatherResponse, error) {
+	if p.Latency > 0 {
+		select {
+		case <-time.After(p.Latency):
+		case <-ctx.Done():
+			return weather.WeatherResponse{}, ctx.Err()
+		}
+	}
+	if p.Err != nil {
+		return weather.WeatherResponse{}, p.Err
+	}
+
+	name, _, _ := strings.Cut(city, ",")
+	p.mu.Lock()
+	data, ok := p.cities[strings.ToLower(strings.TrimSpace(name))]
+	p.mu.Unlock()
+	if !ok {
+		return weather.WeatherResponse{}, &weather.APIError{StatusCode: http.StatusNotFound, Code: "404", Message: "city not found"}
+	}
+	data = inUnits(data, p.Units)
+	data.Source = p.Name()
+	return data, nil
+}
@@ -0,0 +1,74 @@
+package fakeapi
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// serveWttr answers /{location}?format=j1 like wttr.in does, from the same cities and
+// with the same faults, so the fallback between providers can be tried offline.
+// wttr.in has no API key, the key check doesn't apply.
+func (s *Server) serveWttr(w http.ResponseWriter, r *http.Request) {
+	location := path.Base(r.URL.Path)
+	query := url.Values{"q": {location}}
+	if lat, lon, ok := strings.Cut(location, ","); ok {
+		if _, err := strconv.ParseFloat(lat, 64); err == nil {
+			query = url.Values{"lat": {lat}, "lon": {lon}}
+		}
+	}
+
+	data, ok := s.lookup(w, r, query)
+	if !ok {
+		return
+	}
+
+	value := func(s string) []map[string]string { return []map[string]string{{"value": s}} }
+	num := func(f float64) string { return strconv.FormatFloat(f, 'f', -1, 64) }
+	integer := func(f float64) string { return strconv.Itoa(int(f + 0.5)) }
+
+	writeJSON(w, r, map[string]any{
+		"current_condition": []map[string]any{{
+			"temp_C":           integer(data.Main.Temp.Celsius()),
+			"FeelsLikeC":       integer(data.Main.FeelsLike.Celsius()),
+			"humidity":         strconv.Itoa(data.Main.Humidity),
+			"pressure":         strconv.Itoa(data.Main.Pressure),
+			"windspeedKmph":    integer(data.Wind.Speed * 3.6),
+			"windspeedMiles":   integer(data.Wind.Speed * 2.23694),
+			"winddirDegree":    integer(data.Wind.Deg),
+			"cloudcover":       strconv.Itoa(data.Clouds.All),
+			"visibility":       strconv.Itoa(data.Visibility / 1000),
+			"precipMM":         num(data.Rain.OneHour),
+			"weatherDesc":      value(capitalize(data.Description())),
+			"observation_time": data.Time.UTC().Format("03:04 PM"),
+		}},
+		"nearest_area": []map[string]any{{
+			"areaName":  value(data.Name),
+			"country":   value(countryName(data.Sys.Country)),
+			"latitude":  fmt.Sprintf("%.3f", data.Coord.Lat),
+			"longitude": fmt.Sprintf("%.3f", data.Coord.Lon),
+		}},
+	})
+}
+
+// countryName spells out the codes of DefaultCities, wttr.in sends names.
+func countryName(code string) string {
+	names := map[string]string{
+		"CA": "Canada", "GB": "United Kingdom", "FR": "France", "JP": "Japan", "TR": "Turkey",
+		"RU": "Russia", "NO": "Norway", "BR": "Brazil", "US": "United States of America",
+	}
+	if name, ok := names[code]; ok {
+		return name
+	}
+	return code
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
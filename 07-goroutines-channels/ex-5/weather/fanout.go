@@ -0,0 +1,149 @@
+package weather
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// WeatherResult is what every fan-out goroutine sends back on the results channel.
+type WeatherResult struct {
+	Index    int             // position of the city in the list given to FetchAll
+	City     string          // the city as it was requested
+	Data     WeatherResponse // zero value when Err is set
+	Err      error
+	Duration time.Duration // how long this city took, including waiting for its timeout
+	Attempts int           // number of requests sent, by every provider that was asked
+	Cached   bool          // answered by the Cache without a request of its own
+	Wait     time.Duration // part of Duration spent waiting for the rate limiter
+
+	Retried   bool // a provider had to repeat a request
+	Fallbacks int  // providers of a Fallback that failed before the last one asked
+}
+
+// TimedOut reports whether the fetch was abandoned because a deadline passed,
+// either the per-city timeout or the overall deadline of the fan-out context.
+func (r WeatherResult) TimedOut() bool {
+	return errors.Is(r.Err, context.DeadlineExceeded)
+}
+
+// FanOutOptions tunes FetchAll and FetchForecasts. The zero value means
+// "no per-city timeout" and "one goroutine per city".
+type FanOutOptions struct {
+	CityTimeout time.Duration // deadline for a single city, 0 disables it
+	Workers     int           // size of the worker pool, 0 (or less) starts one goroutine per city
+	Order       Order         // order of the results on the channel, "" streams them as they arrive
+
+	// FailFast cancels the cities still running or queued as soon as one fails, they
+	// report context.Canceled. Without it every city is fetched whatever the others do.
+	FailFast bool
+}
+
+// FetchAll fetches every city concurrently and streams the results back, in completion
+// order by default or as chosen by opts.Order. The returned channel is closed once every
+// city has reported, so callers can simply range over it.
+//
+// By default one goroutine is started per city. With opts.Workers set, a fixed pool of
+// workers pulls cities from a job queue instead, which keeps the number of concurrent
+// requests bounded no matter how long the city list is.
+//
+// ctx carries the overall deadline: when it expires all in-flight requests are
+// cancelled and the remaining cities report context.DeadlineExceeded.
+func (c *Client) FetchAll(ctx context.Context, cities []string, opts FanOutOptions) <-chan WeatherResult {
+	return Ordered(fanOut(ctx, cities, opts, c.fetchCurrent), opts.Order)
+}
+
+// fetchCurrent is the per-city work of FetchAll.
+func (c *Client) fetchCurrent(ctx context.Context, index int, city string) WeatherResult {
+	defer c.Metrics.trackInflight()()
+
+	start := time.Now()
+	data, info, err := c.current(ctx, city)
+	return WeatherResult{
+		Index:    index,
+		City:     city,
+		Data:     data,
+		Err:      err,
+		Duration: time.Since(start),
+		Attempts: info.attempts,
+		Cached:   info.cached,
+		Wait:     info.wait,
+		Retried:  info.didRetry(),
+	}
+}
+
+// fanOut runs fetch for every city, either one goroutine per city or on a worker pool,
+// and sends the results on a channel that is closed once all of them are in.
+// It is shared by every endpoint, R is the result type of that endpoint.
+func fanOut[R result](ctx context.Context, cities []string, opts FanOutOptions, fetch func(ctx context.Context, index int, city string) R) <-chan R {
+	ch := make(chan R)
+	var wg sync.WaitGroup
+
+	// cancelAll is how fail-fast stops the others: they all share this context
+	ctx, cancelAll := context.WithCancel(ctx)
+
+	// every city gets its own timeout derived from the parent context
+	run := func(index int, city string) R {
+		ctx := ctx
+		if opts.CityTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, opts.CityTimeout)
+			defer cancel()
+		}
+		r := fetch(ctx, index, city)
+		if opts.FailFast && r.failed() {
+			cancelAll()
+		}
+		return r
+	}
+
+	if opts.Workers > 0 {
+		startPool(cities, opts.Workers, run, ch, &wg)
+	} else {
+		wg.Add(len(cities))
+		for i, city := range cities {
+			go func() {
+				defer wg.Done()
+				ch <- run(i, city)
+			}()
+		}
+	}
+
+	// close the channel once all senders are done, same as the ex-4 pattern
+	go func() {
+		wg.Wait()
+		cancelAll()
+		close(ch)
+	}()
+
+	return ch
+}
+
+// job is one entry of the worker pool queue.
+type job struct {
+	index int
+	city  string
+}
+
+// startPool launches a fixed number of workers that share one job queue.
+// The queue is buffered to hold the whole city list, so filling it never blocks
+// and the workers can drain it at their own pace.
+func startPool[R any](cities []string, workers int, run func(index int, city string) R, ch chan<- R, wg *sync.WaitGroup) {
+	jobs := make(chan job, len(cities))
+	for i, city := range cities {
+		jobs <- job{index: i, city: city}
+	}
+	close(jobs) // workers stop once the queue is empty
+
+	workers = min(workers, len(cities))
+	wg.Add(workers)
+	for range workers {
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				ch <- run(j.index, j.city)
+			}
+		}()
+	}
+}
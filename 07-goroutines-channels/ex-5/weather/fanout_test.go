@@ -0,0 +1,104 @@
+package weather_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang-fast-start/07-goroutines-channels/ex-5/weather"
+	"golang-fast-start/07-goroutines-channels/ex-5/weather/fakeapi"
+)
+
+// BenchmarkFetchAll compares the fan-out modes against a local server that sleeps for a
+// fixed latency per request, like a slow remote API would. No API key needed:
+//
+//	go test -bench FetchAll ./07-goroutines-channels/ex-5/weather
+//
+// A pool is bounded by cities / workers * latency, so a small pool is clearly slower,
+// while a large one keeps up with a goroutine per city and still caps the number of
+// requests in flight.
+func BenchmarkFetchAll(b *testing.B) {
+	const (
+		cityCount = 1000
+		latency   = 20 * time.Millisecond
+	)
+
+	fake := fakeapi.New()
+	fake.Latency = latency
+	fake.Synthesize = true // the bench cities are made up
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	client := weather.NewClient("bench")
+	client.BaseURL = srv.URL
+	// the default transport keeps only 2 idle connections per host, which would
+	// make every mode pay for new TCP handshakes and hide the difference
+	client.HTTPClient = &http.Client{Transport: &http.Transport{MaxIdleConnsPerHost: cityCount}}
+
+	cities := make([]string, cityCount)
+	for i := range cities {
+		cities[i] = fmt.Sprintf("city-%d", i)
+	}
+
+	for _, workers := range []int{0, 10, 50, 100, 500} {
+		name := fmt.Sprintf("workers=%d", workers)
+		if workers == 0 {
+			name = "unbounded"
+		}
+		b.Run(name, func(b *testing.B) {
+			for b.Loop() {
+				for result := range client.FetchAll(context.Background(), cities, weather.FanOutOptions{Workers: workers}) {
+					if result.Err != nil {
+						b.Error(result.Err) // keep receiving, every goroutine has to be able to send
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestFetchAllCityTimeout(t *testing.T) {
+	fake := fakeapi.New()
+	fake.SetFault("Paris", fakeapi.Fault{Latency: time.Second})
+	fake.SetFault("Tokyo", fakeapi.Fault{Status: http.StatusInternalServerError})
+	client := newFakeClient(t, fake)
+
+	cities := []string{"London", "Paris", "Tokyo", "Oslo"}
+	opts := weather.FanOutOptions{CityTimeout: 100 * time.Millisecond, Workers: 2, Order: weather.OrderInput}
+	start := time.Now()
+	var results []weather.WeatherResult
+	for r := range client.FetchAll(context.Background(), cities, opts) {
+		results = append(results, r)
+	}
+	elapsed := time.Since(start)
+
+	if len(results) != len(cities) {
+		t.Fatalf("%d results for %d cities", len(results), len(cities))
+	}
+	for i, r := range results {
+		if r.City != cities[i] {
+			t.Errorf("result %d is %v, want %v", i, r.City, cities[i])
+		}
+	}
+	// the slow city only held up its own worker until the timeout
+	if elapsed > 500*time.Millisecond {
+		t.Errorf("fan-out took %v, the slow city wasn't cut off", elapsed)
+	}
+
+	s := weather.Summarize(results, elapsed)
+	if s.Succeeded != 2 {
+		t.Errorf("%d cities succeeded, want London and Oslo: %v", s.Succeeded, s.Failed)
+	}
+	if got := s.Failed["timeout"]; len(got) != 1 || got[0] != "Paris" {
+		t.Errorf("timed out %v, want [Paris]", got)
+	}
+	if got := s.Failed["server"]; len(got) != 1 || got[0] != "Tokyo" {
+		t.Errorf("server errors %v, want [Tokyo]", got)
+	}
+	if !results[1].TimedOut() || results[2].TimedOut() {
+		t.Errorf("TimedOut: Paris %v, Tokyo %v", results[1].TimedOut(), results[2].TimedOut())
+	}
+}
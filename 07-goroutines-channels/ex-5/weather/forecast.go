@@ -0,0 +1,153 @@
+package weather
+
+import (
+	"context"
+	"time"
+)
+
+// Forecast models the 5 day / 3 hour forecast of /data/2.5/forecast.
+// See https://openweathermap.org/forecast5#JSON
+type Forecast struct {
+	City    ForecastCity    `json:"city"`
+	Count   int             `json:"cnt"`
+	Entries []ForecastEntry `json:"list"` // one entry every 3 hours, up to 40
+}
+
+type ForecastCity struct {
+	ID         int      `json:"id"`
+	Name       string   `json:"name"`
+	Coord      Coord    `json:"coord"`
+	Country    string   `json:"country"`
+	Population int      `json:"population"`
+	Timezone   int      `json:"timezone"` // shift from UTC in seconds
+	Sunrise    UnixTime `json:"sunrise"`
+	Sunset     UnixTime `json:"sunset"`
+}
+
+// ForecastEntry is the forecast for one 3 hour step.
+type ForecastEntry struct {
+	Time          UnixTime    `json:"dt"`
+	Main          Main        `json:"main"`
+	Conditions    []Condition `json:"weather"`
+	Clouds        Clouds      `json:"clouds"`
+	Wind          Wind        `json:"wind"`
+	Visibility    int         `json:"visibility"`
+	Precipitation float64     `json:"pop"` // probability of precipitation, 0 to 1
+	Rain          Volume      `json:"rain"`
+	Snow          Volume      `json:"snow"`
+}
+
+// DailySummary aggregates the forecast entries of one local calendar day.
+type DailySummary struct {
+	Date    time.Time // midnight of the day in the city's time zone
+	Min     Temperature
+	Max     Temperature
+	Avg     Temperature
+	Entries int // how many 3 hour steps the day is made of, the first and last day are partial
+}
+
+// Location returns the city's fixed UTC offset.
+func (f Forecast) Location() *time.Location {
+	return time.FixedZone(f.City.Name, f.City.Timezone)
+}
+
+// Daily groups the entries by local date and returns min/max/avg of temp per day, in
+// chronological order. The unit of the summaries is the unit of the entries.
+func (f Forecast) Daily() []DailySummary {
+	loc := f.Location()
+	var days []DailySummary
+	var sum float64
+
+	for _, entry := range f.Entries {
+		local := entry.Time.In(loc)
+		date := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+		temp := entry.Main.Temp
+
+		// entries are sorted by time, so a new date always means a new day
+		if len(days) == 0 || !days[len(days)-1].Date.Equal(date) {
+			days = append(days, DailySummary{Date: date, Min: temp, Max: temp})
+			sum = 0
+		}
+
+		day := &days[len(days)-1]
+		if temp.Value < day.Min.Value {
+			day.Min = temp
+		}
+		if temp.Value > day.Max.Value {
+			day.Max = temp
+		}
+		day.Entries++
+		sum += temp.Value
+		day.Avg = Temperature{Value: sum / float64(day.Entries), Unit: temp.Unit}
+	}
+	return days
+}
+
+// Forecast fetches the 5 day / 3 hour forecast for a single city.
+// Retries and the rate limiter apply like for Current, the caches only hold current weather.
+func (c *Client) Forecast(ctx context.Context, city string) (Forecast, error) {
+	data, _, err := c.forecast(ctx, city)
+	return data, err
+}
+
+func (c *Client) forecast(ctx context.Context, city string) (data Forecast, info fetchInfo, err error) {
+	defer func() { c.Metrics.observeError(err) }()
+
+	query, geo, err := c.queryValues(ctx, city)
+	if err != nil {
+		return data, geo, err
+	}
+
+	_, info, err = c.fetchJSON(ctx, c.BaseURL+"/forecast", query, validators{}, &data)
+	info.wait += geo.wait
+
+	unit := c.Units.TempUnit()
+	for i := range data.Entries {
+		data.Entries[i].Main.setTempUnit(unit)
+	}
+	return data, info, err
+}
+
+// ForecastResult is the FetchForecasts counterpart of WeatherResult.
+type ForecastResult struct {
+	Index    int
+	City     string
+	Data     Forecast
+	Err      error
+	Duration time.Duration
+	Attempts int
+	Wait     time.Duration
+}
+
+func (r ForecastResult) index() int   { return r.Index }
+func (r ForecastResult) failed() bool { return r.Err != nil }
+func (r ForecastResult) name() string { return r.Data.City.Name }
+
+// kelvin sorts forecasts by the temperature of the nearest 3 hour step.
+func (r ForecastResult) kelvin() float64 {
+	if len(r.Data.Entries) == 0 {
+		return 0
+	}
+	return r.Data.Entries[0].Main.Temp.Kelvin()
+}
+
+// FetchForecasts is FetchAll for the forecast endpoint: same options, same fan-out.
+func (c *Client) FetchForecasts(ctx context.Context, cities []string, opts FanOutOptions) <-chan ForecastResult {
+	return Ordered(fanOut(ctx, cities, opts, c.fetchForecast), opts.Order)
+}
+
+func (c *Client) fetchForecast(ctx context.Context, index int, city string) ForecastResult {
+	defer c.Metrics.trackInflight()()
+
+	start := time.Now()
+	data, info, err := c.forecast(ctx, city)
+	return ForecastResult{
+		Index:    index,
+		City:     city,
+		Data:     data,
+		Err:      err,
+		Duration: time.Since(start),
+		Attempts: info.attempts,
+		Wait:     info.wait,
+	}
+}
@@ -0,0 +1,165 @@
+package weather
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Query identifies a place in one of the three ways the API supports.
+// Exactly one of Name, ID or Coord is set.
+type Query struct {
+	Name  string // "London" or "London,GB", optionally "Springfield,IL,US"
+	ID    int    // OpenWeatherMap city ID
+	Coord *Coord
+}
+
+// ParseQuery reads the string forms used on the command line and in city lists:
+//
+//	London            name
+//	London,GB         name with ISO 3166 country code
+//	id:2643743        city ID
+//	51.5085,-0.1257   latitude,longitude
+func ParseQuery(s string) (Query, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Query{}, fmt.Errorf("empty city")
+	}
+
+	if rest, ok := strings.CutPrefix(s, "id:"); ok {
+		id, err := strconv.Atoi(rest)
+		if err != nil || id <= 0 {
+			return Query{}, fmt.Errorf("bad city ID in %q", s)
+		}
+		return Query{ID: id}, nil
+	}
+
+	// only two numbers make coordinates, "London,GB" stays a name
+	if latText, lonText, ok := strings.Cut(s, ","); ok {
+		lat, latErr := strconv.ParseFloat(strings.TrimSpace(latText), 64)
+		lon, lonErr := strconv.ParseFloat(strings.TrimSpace(lonText), 64)
+		if latErr == nil && lonErr == nil {
+			if lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+				return Query{}, fmt.Errorf("coordinates out of range in %q", s)
+			}
+			return Query{Coord: &Coord{Lat: lat, Lon: lon}}, nil
+		}
+	}
+
+	return Query{Name: s}, nil
+}
+
+// String is the inverse of ParseQuery.
+func (q Query) String() string {
+	switch {
+	case q.ID != 0:
+		return "id:" + strconv.Itoa(q.ID)
+	case q.Coord != nil:
+		return strconv.FormatFloat(q.Coord.Lat, 'f', -1, 64) + "," + strconv.FormatFloat(q.Coord.Lon, 'f', -1, 64)
+	}
+	return q.Name
+}
+
+// values is the query string for the weather and forecast endpoints.
+func (q Query) values() url.Values {
+	values := url.Values{}
+	switch {
+	case q.ID != 0:
+		values.Set("id", strconv.Itoa(q.ID))
+	case q.Coord != nil:
+		values.Set("lat", strconv.FormatFloat(q.Coord.Lat, 'f', -1, 64))
+		values.Set("lon", strconv.FormatFloat(q.Coord.Lon, 'f', -1, 64))
+	default:
+		values.Set("q", q.Name)
+	}
+	return values
+}
+
+// queryValues parses city and, with ResolveNames, swaps a name for its coordinates.
+func (c *Client) queryValues(ctx context.Context, city string) (url.Values, fetchInfo, error) {
+	q, err := ParseQuery(city)
+	if err != nil || q.Name == "" || !c.ResolveNames {
+		return q.values(), fetchInfo{}, err
+	}
+
+	if resolved, ok := c.place(q.Name); ok {
+		return resolved.values(), fetchInfo{}, nil
+	}
+
+	places, info, err := c.geocode(ctx, q.Name, 1)
+	if err != nil {
+		return nil, info, err
+	}
+	if len(places) == 0 {
+		return nil, info, &APIError{StatusCode: http.StatusNotFound, Code: "404", Message: "city not found"}
+	}
+
+	resolved := Query{Coord: &Coord{Lat: places[0].Lat, Lon: places[0].Lon}}
+	c.rememberPlace(q.Name, resolved)
+	return resolved.values(), info, nil
+}
+
+// maxPlaces bounds the names a Client remembers, a server resolves whatever its callers ask for.
+const maxPlaces = 10000
+
+func (c *Client) place(name string) (Query, bool) {
+	c.placesMu.Lock()
+	defer c.placesMu.Unlock()
+	q, ok := c.places[strings.ToLower(name)]
+	return q, ok
+}
+
+// rememberPlace stores a resolved name. Places don't move, so when the map is full any
+// entry can make room: a forgotten name is only geocoded again.
+func (c *Client) rememberPlace(name string, q Query) {
+	c.placesMu.Lock()
+	defer c.placesMu.Unlock()
+	if c.places == nil {
+		c.places = make(map[string]Query)
+	}
+	if len(c.places) >= maxPlaces {
+		for k := range c.places {
+			delete(c.places, k)
+			break
+		}
+	}
+	c.places[strings.ToLower(name)] = q
+}
+
+// Place is one match of the geocoding API.
+type Place struct {
+	Name       string            `json:"name"`
+	LocalNames map[string]string `json:"local_names"` // name by language code, e.g. "tr": "Londra"
+	Lat        float64           `json:"lat"`
+	Lon        float64           `json:"lon"`
+	Country    string            `json:"country"`
+	State      string            `json:"state"`
+}
+
+// Geocode resolves "City", "City,CountryCode" or "City,StateCode,CountryCode" to up to
+// limit places, best match first. Use it to see which Springfield a name resolves to.
+func (c *Client) Geocode(ctx context.Context, name string, limit int) ([]Place, error) {
+	places, _, err := c.geocode(ctx, name, limit)
+	return places, err
+}
+
+func (c *Client) geocode(ctx context.Context, name string, limit int) ([]Place, fetchInfo, error) {
+	query := url.Values{}
+	query.Set("q", name)
+	query.Set("limit", strconv.Itoa(max(limit, 1)))
+
+	var places []Place
+	_, info, err := c.fetchJSON(ctx, c.geoBaseURL()+"/direct", query, validators{}, &places)
+	return places, info, err
+}
+
+// geoBaseURL follows BaseURL: a client pointed at a local server geocodes there too.
+func (c *Client) geoBaseURL() string {
+	if c.GeoBaseURL != "" {
+		return c.GeoBaseURL
+	}
+	return strings.TrimSuffix(c.BaseURL, "/data/2.5") + "/geo/1.0"
+}
@@ -0,0 +1,172 @@
+package weather
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics counts what a Client does and writes it in the Prometheus text exposition
+// format, so a periodic job can be scraped without pulling in a metrics library.
+// A nil *Metrics is valid and records nothing, which is what a Client without
+// Metrics uses.
+type Metrics struct {
+	mu       sync.Mutex
+	requests map[string]float64    // endpoint, code
+	latency  map[string]*histogram // endpoint
+	errors   map[string]float64    // category
+	cache    map[string]float64    // layer, result
+	rateWait float64               // seconds
+	inflight float64
+}
+
+// latencyBuckets are the upper bounds in seconds of the request latency histogram.
+var latencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type histogram struct {
+	counts []float64 // per bucket, not cumulative, the last one is +Inf
+	sum    float64
+	count  float64
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		requests: make(map[string]float64),
+		latency:  make(map[string]*histogram),
+		errors:   make(map[string]float64),
+		cache:    make(map[string]float64),
+	}
+}
+
+// observeRequest records one HTTP request, code is "error" when no response came back.
+func (m *Metrics) observeRequest(endpoint, code string, d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests[labels("endpoint", endpoint, "code", code)]++
+
+	h, ok := m.latency[endpoint]
+	if !ok {
+		h = &histogram{counts: make([]float64, len(latencyBuckets)+1)}
+		m.latency[endpoint] = h
+	}
+	seconds := d.Seconds()
+	i, _ := slices.BinarySearch(latencyBuckets, seconds)
+	h.counts[i]++
+	h.sum += seconds
+	h.count++
+}
+
+// observeError records a failed fetch of this Client, once per city and not once per
+// attempt. Inside a Fallback it still counts when the next provider answers the city,
+// the metric is about the OpenWeatherMap API and not about what the user got.
+func (m *Metrics) observeError(err error) {
+	if m == nil || err == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.errors[labels("category", ErrorCategory(err))]++
+}
+
+// observeCache records a lookup in the memory or disk cache.
+func (m *Metrics) observeCache(layer string, hit bool) {
+	if m == nil {
+		return
+	}
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cache[labels("layer", layer, "result", result)]++
+}
+
+func (m *Metrics) observeRateWait(d time.Duration) {
+	if m == nil || d <= 0 {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rateWait += d.Seconds()
+}
+
+// trackInflight counts a running fetch goroutine, call the returned func when it's done.
+func (m *Metrics) trackInflight() func() {
+	if m == nil {
+		return func() {}
+	}
+	m.mu.Lock()
+	m.inflight++
+	m.mu.Unlock()
+	return func() {
+		m.mu.Lock()
+		m.inflight--
+		m.mu.Unlock()
+	}
+}
+
+// WriteTo writes every metric in the text exposition format.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var b strings.Builder
+
+	writeFamily(&b, "weather_requests_total", "counter", "HTTP requests sent to the weather API.", m.requests)
+
+	b.WriteString("# HELP weather_request_duration_seconds Latency of HTTP requests to the weather API.\n")
+	b.WriteString("# TYPE weather_request_duration_seconds histogram\n")
+	for _, endpoint := range slices.Sorted(maps.Keys(m.latency)) {
+		h := m.latency[endpoint]
+		cumulative := 0.0
+		for i, bound := range latencyBuckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(&b, "weather_request_duration_seconds_bucket{endpoint=%q,le=\"%g\"} %g\n", endpoint, bound, cumulative)
+		}
+		fmt.Fprintf(&b, "weather_request_duration_seconds_bucket{endpoint=%q,le=\"+Inf\"} %g\n", endpoint, h.count)
+		fmt.Fprintf(&b, "weather_request_duration_seconds_sum{endpoint=%q} %g\n", endpoint, h.sum)
+		fmt.Fprintf(&b, "weather_request_duration_seconds_count{endpoint=%q} %g\n", endpoint, h.count)
+	}
+
+	writeFamily(&b, "weather_fetch_errors_total", "counter", "Cities the OpenWeatherMap API failed to answer by error category, including the ones a fallback provider answered.", m.errors)
+	writeFamily(&b, "weather_cache_lookups_total", "counter", "Cache lookups by layer and result.", m.cache)
+	writeFamily(&b, "weather_rate_limit_wait_seconds_total", "counter", "Time spent waiting for the client-side rate limiter.", map[string]float64{"": m.rateWait})
+	writeFamily(&b, "weather_inflight_fetches", "gauge", "City fetches currently running.", map[string]float64{"": m.inflight})
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// Handler serves the metrics, mount it on /metrics.
+func (m *Metrics) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		m.WriteTo(w)
+	})
+}
+
+func writeFamily(b *strings.Builder, name, kind, help string, values map[string]float64) {
+	fmt.Fprintf(b, "# HELP %v %v\n# TYPE %v %v\n", name, help, name, kind)
+	for _, labels := range slices.Sorted(maps.Keys(values)) {
+		fmt.Fprintf(b, "%v%v %g\n", name, labels, values[labels])
+	}
+}
+
+// labels renders name/value pairs as {name="value",...}, the map key of a series.
+func labels(pairs ...string) string {
+	parts := make([]string, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		parts = append(parts, fmt.Sprintf("%v=%q", pairs[i], pairs[i+1]))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
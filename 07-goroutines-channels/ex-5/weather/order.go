@@ -0,0 +1,114 @@
+package weather
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Order is the order in which FetchAll delivers results. Fetching is concurrent in
+// every mode, only the delivery changes.
+type Order string
+
+const (
+	OrderArrival Order = "arrival" // as soon as each city completes, non-deterministic
+	OrderInput   Order = "input"   // same order as the city list, streamed as soon as possible
+	OrderName    Order = "name"    // sorted by city name, errors last
+	OrderTemp    Order = "temp"    // sorted by temperature, coldest first, errors last
+)
+
+// ParseOrder accepts the Order names, "" means OrderArrival.
+func ParseOrder(s string) (Order, error) {
+	switch Order(s) {
+	case "", OrderArrival:
+		return OrderArrival, nil
+	case OrderInput, OrderName, OrderTemp:
+		return Order(s), nil
+	}
+	return "", fmt.Errorf("unknown order %q, use arrival, input, name or temp", s)
+}
+
+// result is what Ordered needs to know about WeatherResult and ForecastResult.
+type result interface {
+	index() int
+	failed() bool
+	name() string
+	kelvin() float64
+}
+
+func (r WeatherResult) index() int      { return r.Index }
+func (r WeatherResult) failed() bool    { return r.Err != nil }
+func (r WeatherResult) name() string    { return r.Data.Name }
+func (r WeatherResult) kelvin() float64 { return r.Data.Main.Temp.Kelvin() }
+
+// Ordered reorders a result stream and returns a new channel that is closed when in is.
+// OrderArrival returns in unchanged. OrderInput still streams: a result is sent as soon
+// as every city before it has arrived. The sorted orders have to wait for the last result.
+func Ordered[R result](in <-chan R, order Order) <-chan R {
+	switch order {
+	case OrderInput:
+		out := make(chan R)
+		go streamInInputOrder(in, out)
+		return out
+	case OrderName, OrderTemp:
+		out := make(chan R)
+		go sortAll(in, out, order)
+		return out
+	}
+	return in
+}
+
+// streamInInputOrder holds back results that arrive early until the gap before them is filled.
+func streamInInputOrder[R result](in <-chan R, out chan<- R) {
+	defer close(out)
+
+	pending := make(map[int]R)
+	next := 0
+	for r := range in {
+		pending[r.index()] = r
+		for {
+			r, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			out <- r
+			next++
+		}
+	}
+}
+
+func sortAll[R result](in <-chan R, out chan<- R, order Order) {
+	defer close(out)
+
+	var results []R
+	for r := range in {
+		results = append(results, r)
+	}
+
+	slices.SortStableFunc(results, func(a, b R) int {
+		// errors have no name or temperature, keep them together at the end in input order
+		if a.failed() != b.failed() {
+			if a.failed() {
+				return 1
+			}
+			return -1
+		}
+		if a.failed() {
+			return cmp.Compare(a.index(), b.index())
+		}
+
+		var c int
+		if order == OrderTemp {
+			c = cmp.Compare(a.kelvin(), b.kelvin())
+		} else {
+			c = strings.Compare(strings.ToLower(a.name()), strings.ToLower(b.name()))
+		}
+		return cmp.Or(c, cmp.Compare(a.index(), b.index()))
+	})
+
+	for _, r := range results {
+		out <- r
+	}
+}
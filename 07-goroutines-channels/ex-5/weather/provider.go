@@ -0,0 +1,134 @@
+package weather
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// Provider is anything that can tell the current weather of a city. Much like the engine
+// interface in 05-structs-interfaces lets canMakeIt work for gas and electric engines,
+// FetchAllFrom works for every provider, whatever API sits behind it:
+//
+//	*Client     OpenWeatherMap
+//	*Wttr       wttr.in
+//	Fallback    several providers, the next one is asked when one fails
+//
+// fakeapi.Provider is an in-memory one for experiments.
+//
+// Current returns the response in the shape of the OpenWeatherMap one, the fields a
+// provider doesn't know are left empty. Temperatures carry their unit, so they can be
+// converted no matter which provider answered.
+type Provider interface {
+	Name() string
+	Current(ctx context.Context, city string) (WeatherResponse, error)
+}
+
+// detailedProvider is implemented by the providers of this package, it reports attempts,
+// cache hits and rate limit waits for the fan-out results.
+type detailedProvider interface {
+	current(ctx context.Context, city string) (WeatherResponse, fetchInfo, error)
+}
+
+// Name makes the Client a Provider.
+func (c *Client) Name() string { return "openweathermap" }
+
+// FetchAllFrom is FetchAll for any provider.
+func FetchAllFrom(ctx context.Context, p Provider, cities []string, opts FanOutOptions) <-chan WeatherResult {
+	if c, ok := p.(*Client); ok {
+		return c.FetchAll(ctx, cities, opts) // keeps the metrics of the client
+	}
+	fetch := func(ctx context.Context, index int, city string) WeatherResult {
+		start := time.Now()
+		data, info, err := currentFrom(ctx, p, city)
+		return WeatherResult{
+			Index:     index,
+			City:      city,
+			Data:      data,
+			Err:       err,
+			Duration:  time.Since(start),
+			Attempts:  info.attempts,
+			Cached:    info.cached,
+			Wait:      info.wait,
+			Retried:   info.didRetry(),
+			Fallbacks: info.fallbacks,
+		}
+	}
+	return Ordered(fanOut(ctx, cities, opts, fetch), opts.Order)
+}
+
+// currentFrom asks p, with the details when p has them. A provider from outside this
+// package counts as one attempt.
+func currentFrom(ctx context.Context, p Provider, city string) (WeatherResponse, fetchInfo, error) {
+	if d, ok := p.(detailedProvider); ok {
+		return d.current(ctx, city)
+	}
+	data, err := p.Current(ctx, city)
+	if data.Source == "" {
+		data.Source = p.Name()
+	}
+	return data, fetchInfo{attempts: 1}, err
+}
+
+// Fallback asks its providers in order and returns the first answer, so a city is still
+// served when one API is down, out of quota or doesn't know it.
+type Fallback []Provider
+
+func (f Fallback) Name() string {
+	names := make([]string, len(f))
+	for i, p := range f {
+		names[i] = p.Name()
+	}
+	return strings.Join(names, ">")
+}
+
+func (f Fallback) Current(ctx context.Context, city string) (WeatherResponse, error) {
+	data, _, err := f.current(ctx, city)
+	return data, err
+}
+
+func (f Fallback) current(ctx context.Context, city string) (WeatherResponse, fetchInfo, error) {
+	var total fetchInfo
+	fallbackErr := &FallbackError{}
+
+	for i, p := range f {
+		data, info, err := currentFrom(ctx, p, city)
+		// summed but kept apart from retries: asking the next provider is not a retry
+		total.attempts += info.attempts
+		total.fallbacks = i + info.fallbacks
+		total.retried = total.retried || info.didRetry()
+		total.wait += info.wait
+		if err == nil {
+			total.cached = info.cached
+			return data, total, nil
+		}
+		fallbackErr.Providers = append(fallbackErr.Providers, p.Name())
+		fallbackErr.Errs = append(fallbackErr.Errs, err)
+
+		// out of time: the next provider would only fail the same way
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if len(fallbackErr.Errs) == 1 {
+		return WeatherResponse{}, total, fallbackErr.Errs[0]
+	}
+	return WeatherResponse{}, total, fallbackErr
+}
+
+// FallbackError collects what every provider of a Fallback answered.
+// errors.Is and errors.As look at all of them.
+type FallbackError struct {
+	Providers []string
+	Errs      []error
+}
+
+func (e *FallbackError) Error() string {
+	parts := make([]string, len(e.Errs))
+	for i, err := range e.Errs {
+		parts[i] = e.Providers[i] + ": " + err.Error()
+	}
+	return "all providers failed: " + strings.Join(parts, "; ")
+}
+
+func (e *FallbackError) Unwrap() []error { return e.Errs }
@@ -0,0 +1,106 @@
+package weather_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"golang-fast-start/07-goroutines-channels/ex-5/weather"
+	"golang-fast-start/07-goroutines-channels/ex-5/weather/fakeapi"
+)
+
+func TestFallbackOrder(t *testing.T) {
+	fake := fakeapi.New()
+	fake.SetFault("London", fakeapi.Fault{Status: http.StatusServiceUnavailable})
+	client := newFakeClient(t, fake)
+	backup := fakeapi.NewProvider()
+	chain := weather.Fallback{client, backup}
+
+	if name := chain.Name(); name != "openweathermap>fake" {
+		t.Errorf("name %q", name)
+	}
+
+	results := map[string]weather.WeatherResult{}
+	for r := range weather.FetchAllFrom(context.Background(), chain, []string{"London", "Paris"}, weather.FanOutOptions{}) {
+		results[r.City] = r
+	}
+
+	// Paris is answered by the first provider, the second one is never asked
+	paris := results["Paris"]
+	if paris.Err != nil || paris.Data.Source != "openweathermap" || paris.Fallbacks != 0 || paris.Attempts != 1 {
+		t.Errorf("Paris: source %q, %d fallbacks, %d attempts, %v", paris.Data.Source, paris.Fallbacks, paris.Attempts, paris.Err)
+	}
+
+	// London is retried on the first provider, then answered by the second
+	london := results["London"]
+	if london.Err != nil || london.Data.Source != "fake" || london.Data.Name != "London" {
+		t.Fatalf("London: source %q, %v", london.Data.Source, london.Err)
+	}
+	if london.Fallbacks != 1 || london.Attempts != fastRetry.MaxAttempts+1 || !london.Retried {
+		t.Errorf("London: %d fallbacks, %d attempts, retried %v, want 1, %d and true",
+			london.Fallbacks, london.Attempts, london.Retried, fastRetry.MaxAttempts+1)
+	}
+	if hits := fake.Hits("London"); hits != fastRetry.MaxAttempts {
+		t.Errorf("server hits %d, want %d", hits, fastRetry.MaxAttempts)
+	}
+
+	s := weather.Summarize([]weather.WeatherResult{london, paris}, 0)
+	if s.FellBack != 1 || s.Retried != 1 || s.Succeeded != 2 {
+		t.Errorf("summary %+v", s)
+	}
+}
+
+func TestFallbackError(t *testing.T) {
+	fake := fakeapi.New()
+	client := newFakeClient(t, fake)
+	backup := fakeapi.NewProvider()
+	backup.Err = &weather.APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: time.Minute}
+	chain := weather.Fallback{client, backup}
+
+	_, err := chain.Current(context.Background(), "Nowhere")
+
+	var fallbackErr *weather.FallbackError
+	if !errors.As(err, &fallbackErr) {
+		t.Fatalf("got %T: %v, want a *FallbackError", err, err)
+	}
+	if len(fallbackErr.Providers) != 2 || fallbackErr.Providers[0] != "openweathermap" || fallbackErr.Providers[1] != "fake" {
+		t.Errorf("providers %v", fallbackErr.Providers)
+	}
+	// every provider's error is reachable
+	if !errors.Is(err, weather.ErrCityNotFound) || !errors.Is(err, weather.ErrRateLimited) {
+		t.Errorf("errors.Is misses one of the providers: %v", err)
+	}
+	if errors.Is(err, weather.ErrUnauthorized) {
+		t.Errorf("errors.Is(%v, ErrUnauthorized) = true", err)
+	}
+	var apiErr *weather.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("errors.As finds %+v, want the first provider's 404", apiErr)
+	}
+
+	// a single provider's error is returned as it is
+	_, err = weather.Fallback{client}.Current(context.Background(), "Nowhere")
+	if errors.As(err, &fallbackErr) || !errors.Is(err, weather.ErrCityNotFound) {
+		t.Errorf("one provider, but got %T: %v", err, err)
+	}
+}
+
+func TestFallbackStopsWhenOutOfTime(t *testing.T) {
+	fake := fakeapi.New()
+	fake.Latency = time.Second
+	client := newFakeClient(t, fake)
+	backup := fakeapi.NewProvider()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	r := <-weather.FetchAllFrom(ctx, weather.Fallback{client, backup}, []string{"Paris"}, weather.FanOutOptions{})
+	if !r.TimedOut() {
+		t.Errorf("got %v, want a timeout", r.Err)
+	}
+	var fallbackErr *weather.FallbackError
+	if errors.As(r.Err, &fallbackErr) {
+		t.Errorf("the next provider was asked after the deadline: %v", r.Err)
+	}
+}
@@ -0,0 +1,78 @@
+package weather
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Clock is the source of time for the Limiter. The real clock is used by default,
+// a fake one lets the pacing be checked without actually sleeping.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// Limiter is a token bucket shared by every goroutine of a client.
+// The bucket holds up to burst tokens and refills at a steady rate, each request takes
+// one token and waits when the bucket is empty, so after the initial burst requests are
+// spread evenly instead of hitting the API all at once.
+type Limiter struct {
+	interval time.Duration // time to refill one token
+	burst    int
+	clock    Clock
+
+	mu     sync.Mutex
+	tokens float64 // can go negative: that's the queue of goroutines already waiting
+	last   time.Time
+}
+
+// NewLimiter allows limit requests per period with bursts of up to burst requests,
+// e.g. the OpenWeatherMap free tier is NewLimiter(60, time.Minute, 1).
+func NewLimiter(limit int, per time.Duration, burst int) *Limiter {
+	return NewLimiterWithClock(limit, per, burst, realClock{})
+}
+
+// NewLimiterWithClock is NewLimiter with a custom clock.
+func NewLimiterWithClock(limit int, per time.Duration, burst int, clock Clock) *Limiter {
+	burst = max(burst, 1)
+	return &Limiter{
+		interval: per / time.Duration(max(limit, 1)),
+		burst:    burst,
+		clock:    clock,
+		tokens:   float64(burst), // start full so the first burst goes out immediately
+		last:     clock.Now(),
+	}
+}
+
+// Wait blocks until the caller may send a request and returns how long it waited.
+// If ctx is done first, the reserved token is given back and ctx.Err() is returned.
+func (l *Limiter) Wait(ctx context.Context) (time.Duration, error) {
+	l.mu.Lock()
+	now := l.clock.Now()
+	l.tokens = min(l.tokens+float64(now.Sub(l.last))/float64(l.interval), float64(l.burst))
+	l.last = now
+	l.tokens--
+	// a negative balance means we are in line behind other goroutines: wait until our token is refilled
+	wait := time.Duration(-l.tokens * float64(l.interval))
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return 0, nil
+	}
+
+	select {
+	case <-l.clock.After(wait):
+		return wait, nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return l.clock.Now().Sub(now), ctx.Err()
+	}
+}
@@ -0,0 +1,164 @@
+package weather
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// manualClock only moves when the test calls Advance, so the pacing can be checked
+// exactly and without sleeping.
+type manualClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []manualTimer
+}
+
+type manualTimer struct {
+	at time.Time
+	ch chan time.Time
+}
+
+func newManualClock() *manualClock {
+	return &manualClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *manualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *manualClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := manualTimer{at: c.now.Add(d), ch: make(chan time.Time, 1)}
+	c.timers = append(c.timers, t)
+	return t.ch
+}
+
+// Advance moves the clock forward and fires the timers that are due.
+func (c *manualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		if t.at.After(c.now) {
+			pending = append(pending, t)
+			continue
+		}
+		t.ch <- c.now
+	}
+	c.timers = pending
+}
+
+// waitForTimers blocks until n goroutines are sleeping on the clock.
+func (c *manualClock) waitForTimers(t *testing.T, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		c.mu.Lock()
+		got := len(c.timers)
+		c.mu.Unlock()
+		if got == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines waiting on the clock, want %d", got, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+type waitResult struct {
+	waited time.Duration
+	err    error
+}
+
+// startWait calls Wait in a goroutine and returns once it is sleeping on the clock.
+func startWait(t *testing.T, ctx context.Context, l *Limiter, clock *manualClock, sleeping int) <-chan waitResult {
+	t.Helper()
+	ch := make(chan waitResult, 1)
+	go func() {
+		waited, err := l.Wait(ctx)
+		ch <- waitResult{waited, err}
+	}()
+	clock.waitForTimers(t, sleeping)
+	return ch
+}
+
+// receive fails the test instead of hanging when Wait didn't return, e.g. because it
+// sleeps longer than the clock was advanced.
+func receive(t *testing.T, ch <-chan waitResult) waitResult {
+	t.Helper()
+	select {
+	case r := <-ch:
+		return r
+	case <-time.After(time.Second):
+		t.Fatal("Wait didn't return")
+		return waitResult{}
+	}
+}
+
+func TestLimiterPacing(t *testing.T) {
+	const interval = 100 * time.Millisecond
+	clock := newManualClock()
+	l := NewLimiterWithClock(10, time.Second, 3, clock)
+
+	// the bucket starts full, the burst goes out without waiting
+	for i := range 3 {
+		waited, err := l.Wait(context.Background())
+		if err != nil || waited != 0 {
+			t.Fatalf("call %d of the burst: waited %v, %v, want 0", i+1, waited, err)
+		}
+	}
+
+	// then every caller queues one interval behind the previous one
+	var calls []<-chan waitResult
+	for i := range 3 {
+		calls = append(calls, startWait(t, context.Background(), l, clock, i+1))
+	}
+	clock.Advance(3 * interval)
+	for i, ch := range calls {
+		r := receive(t, ch)
+		if want := time.Duration(i+1) * interval; r.err != nil || r.waited != want {
+			t.Errorf("call %d after the burst: waited %v, %v, want %v", i+1, r.waited, r.err, want)
+		}
+	}
+
+	// the bucket refills at one token per interval, up to burst
+	clock.Advance(10 * interval)
+	for i := range 3 {
+		if waited, _ := l.Wait(context.Background()); waited != 0 {
+			t.Fatalf("call %d after the refill: waited %v, want 0", i+1, waited)
+		}
+	}
+}
+
+func TestLimiterCancelReturnsToken(t *testing.T) {
+	const interval = 100 * time.Millisecond
+	clock := newManualClock()
+	l := NewLimiterWithClock(10, time.Second, 1, clock)
+
+	if waited, _ := l.Wait(context.Background()); waited != 0 {
+		t.Fatalf("first call waited %v, want 0", waited)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelled := startWait(t, ctx, l, clock, 1)
+	cancel()
+	if r := receive(t, cancelled); !errors.Is(r.err, context.Canceled) {
+		t.Fatalf("cancelled call returned %v, want context.Canceled", r.err)
+	}
+
+	// the cancelled caller gave its place in line back: the next one waits one
+	// interval, not two
+	next := startWait(t, context.Background(), l, clock, 2) // the cancelled timer is still registered
+	clock.Advance(interval)
+	if r := receive(t, next); r.err != nil || r.waited != interval {
+		t.Errorf("call after the cancelled one: waited %v, %v, want %v", r.waited, r.err, interval)
+	}
+}
@@ -0,0 +1,59 @@
+package weather
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// redacted replaces the API key wherever it would end up in an error message or a log line.
+const redacted = "REDACTED"
+
+// redactURL hides the appid parameter of a request URL.
+func (c *Client) redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return c.redactString(raw)
+	}
+	query := u.Query()
+	if query.Has("appid") {
+		query.Set("appid", redacted)
+		u.RawQuery = query.Encode()
+	}
+	return c.redactString(u.String())
+}
+
+func (c *Client) redactString(s string) string {
+	if c.APIKey == "" {
+		return s
+	}
+	return strings.ReplaceAll(s, c.APIKey, redacted)
+}
+
+// redact makes sure err can be printed without leaking the API key.
+// net/http reports transport failures as *url.Error with the full request URL, key included,
+// so its URL is rewritten in place (it was created for this request only). Anything else
+// that still mentions the key is wrapped, errors.Is and errors.As keep working through Unwrap.
+func (c *Client) redact(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		urlErr.URL = c.redactURL(urlErr.URL)
+	}
+
+	if c.APIKey == "" || !strings.Contains(err.Error(), c.APIKey) {
+		return err
+	}
+	return &redactedError{err: err, msg: c.redactString(err.Error())}
+}
+
+type redactedError struct {
+	err error
+	msg string
+}
+
+func (e *redactedError) Error() string { return e.msg }
+func (e *redactedError) Unwrap() error { return e.err }
@@ -0,0 +1,127 @@
+package weather
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const testKey = "s3cr3t-api-key-0123456789"
+
+// newTestClient returns a client without retries, so every test sends a single request.
+func newTestClient(baseURL string) *Client {
+	c := NewClient(testKey)
+	c.BaseURL = baseURL
+	c.Retry = RetryPolicy{}
+	return c
+}
+
+func assertNoKey(t *testing.T, what, s string) {
+	t.Helper()
+	if strings.Contains(s, testKey) {
+		t.Errorf("%v contains the API key: %v", what, s)
+	}
+}
+
+func TestRedactTransportError(t *testing.T) {
+	// a port nobody listens on: net/http reports the full URL, appid included
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	_, err = newTestClient("http://"+addr).Current(context.Background(), "London")
+	if err == nil {
+		t.Fatal("expected an error from a closed port")
+	}
+	assertNoKey(t, "error", err.Error())
+
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		t.Fatalf("errors.As(*url.Error) failed on %T: %v", err, err)
+	}
+	assertNoKey(t, "url.Error.URL", urlErr.URL)
+}
+
+func TestRedactAPIError(t *testing.T) {
+	tests := []struct {
+		status   int
+		sentinel error
+	}{
+		{http.StatusUnauthorized, ErrUnauthorized},
+		{http.StatusInternalServerError, ErrServer},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			// a server that echoes the key back in its error message, the way some
+			// proxies and API gateways do
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				fmt.Fprintf(w, `{"cod":%d,"message":"request with appid %v failed"}`, tt.status, r.URL.Query().Get("appid"))
+			}))
+			defer srv.Close()
+
+			_, err := newTestClient(srv.URL).Current(context.Background(), "London")
+			if err == nil {
+				t.Fatalf("expected an error for status %d", tt.status)
+			}
+			assertNoKey(t, "error", err.Error())
+			if !strings.Contains(err.Error(), redacted) {
+				t.Errorf("error %q doesn't say the key was redacted", err)
+			}
+
+			var redactedErr *redactedError
+			if !errors.As(err, &redactedErr) {
+				t.Fatalf("expected a *redactedError, got %T", err)
+			}
+			if !errors.Is(err, tt.sentinel) {
+				t.Errorf("errors.Is(err, %v) = false through the redacted error", tt.sentinel)
+			}
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != tt.status {
+				t.Errorf("errors.As(*APIError) failed through the redacted error: %v", err)
+			}
+		})
+	}
+}
+
+func TestRedactDebugLog(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"cod":"404","message":"city not found"}`)
+	}))
+	defer srv.Close()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := "http://" + ln.Addr().String()
+	ln.Close()
+
+	var buf bytes.Buffer
+	for _, baseURL := range []string{srv.URL, closed} {
+		c := newTestClient(baseURL)
+		c.Debug = log.New(&buf, "", 0)
+		c.Current(context.Background(), "London")
+	}
+
+	out := buf.String()
+	if strings.Count(out, "\n") != 2 {
+		t.Fatalf("expected one debug line per request, got:\n%v", out)
+	}
+	assertNoKey(t, "debug log", out)
+	if !strings.Contains(out, "appid="+redacted) {
+		t.Errorf("debug log doesn't show the redacted appid:\n%v", out)
+	}
+}
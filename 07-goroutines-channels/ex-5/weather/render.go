@@ -0,0 +1,227 @@
+package weather
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Renderer writes fan-out results in some output format.
+// Render is called once per result, Close once at the end with the total elapsed time.
+// The structured formats leave the elapsed time out, so every line of their output is a
+// row of the same shape, callers report it elsewhere (see Summary).
+type Renderer interface {
+	Render(r WeatherResult) error
+	Close(elapsed time.Duration) error
+}
+
+// Formats lists the names accepted by NewRenderer.
+var Formats = []string{"text", "json", "csv", "table"}
+
+// NewRenderer returns the renderer for format, writing temperatures in unit.
+//
+//	text   human readable lines, the original ex-5 output
+//	json   one JSON object per line (JSON lines)
+//	csv    a header row, then one row per city
+//	table  aligned columns, then an "Elapsed: ..." footer
+func NewRenderer(format string, w io.Writer, unit TempUnit) (Renderer, error) {
+	switch format {
+	case "text":
+		return &textRenderer{w: w, unit: unit}, nil
+	case "json":
+		return &jsonRenderer{enc: json.NewEncoder(w), unit: unit}, nil
+	case "csv":
+		return &csvRenderer{w: csv.NewWriter(w), unit: unit}, nil
+	case "table":
+		return &tableRenderer{w: tabwriter.NewWriter(w, 0, 0, 2, ' ', 0), unit: unit}, nil
+	}
+	return nil, fmt.Errorf("unknown format %q, use one of %v", format, strings.Join(Formats, ", "))
+}
+
+// Record is the flat view of a result shared by the structured formats.
+// Error rows have Error set and the weather fields left empty.
+type Record struct {
+	City        string   `json:"city"`
+	Name        string   `json:"name,omitempty"`
+	Country     string   `json:"country,omitempty"`
+	Temp        *float64 `json:"temp,omitempty"`
+	FeelsLike   *float64 `json:"feels_like,omitempty"`
+	Unit        string   `json:"unit,omitempty"`
+	Humidity    int      `json:"humidity,omitempty"`
+	Pressure    int      `json:"pressure,omitempty"`
+	WindSpeed   float64  `json:"wind_speed,omitempty"`
+	Conditions  string   `json:"conditions,omitempty"`
+	Provider    string   `json:"provider,omitempty"`
+	Error       string   `json:"error,omitempty"`
+	Attempts    int      `json:"attempts"`
+	Cached      bool     `json:"cached"`
+	DurationMS  int64    `json:"duration_ms"`
+	RateLimitMS int64    `json:"rate_limit_wait_ms"`
+}
+
+// NewRecord flattens r, converting temperatures to unit.
+func NewRecord(r WeatherResult, unit TempUnit) Record {
+	rec := Record{
+		City:        r.City,
+		Attempts:    r.Attempts,
+		Cached:      r.Cached,
+		DurationMS:  r.Duration.Milliseconds(),
+		RateLimitMS: r.Wait.Milliseconds(),
+	}
+	if r.Err != nil {
+		rec.Error = r.Err.Error()
+		return rec
+	}
+
+	temp := Round2(r.Data.Main.Temp.In(unit).Value)
+	feelsLike := Round2(r.Data.Main.FeelsLike.In(unit).Value)
+	rec.Name = r.Data.Name
+	rec.Country = r.Data.Sys.Country
+	rec.Temp = &temp
+	rec.FeelsLike = &feelsLike
+	rec.Unit = unit.String()
+	rec.Humidity = r.Data.Main.Humidity
+	rec.Pressure = r.Data.Main.Pressure
+	rec.WindSpeed = Round2(r.Data.Wind.Speed)
+	rec.Conditions = r.Data.Description()
+	rec.Provider = r.Data.Source
+	return rec
+}
+
+// Description joins the condition descriptions, e.g. "light rain, mist".
+func (r WeatherResponse) Description() string {
+	descriptions := make([]string, len(r.Conditions))
+	for i, c := range r.Conditions {
+		descriptions[i] = c.Description
+	}
+	return strings.Join(descriptions, ", ")
+}
+
+// displayName prefers the name the API resolved, a lookup by coordinates far from any
+// city comes back without one.
+func (r WeatherResult) displayName() string {
+	if r.Data.Name != "" {
+		return r.Data.Name
+	}
+	return r.City
+}
+
+type textRenderer struct {
+	w    io.Writer
+	unit TempUnit
+}
+
+func (t *textRenderer) Render(r WeatherResult) error {
+	var err error
+	switch {
+	case r.TimedOut():
+		_, err = fmt.Fprintf(t.w, "Timeout: %v after %v\n", r.City, r.Duration)
+	case errors.Is(r.Err, context.Canceled):
+		_, err = fmt.Fprintf(t.w, "Cancelled: %v\n", r.City) // by -fail-fast, the error is elsewhere
+	case r.Err != nil:
+		_, err = fmt.Fprintf(t.w, "Error: %v: %v\n", r.City, r.Err)
+	default:
+		_, err = fmt.Fprintf(t.w, "City: %v, Temperature: %v, Feels like: %v, Humidity: %v%%\n", r.displayName(), r.Data.Main.Temp.In(t.unit).Format(1), r.Data.Main.FeelsLike.In(t.unit).Format(1), r.Data.Main.Humidity)
+	}
+	return err
+}
+
+func (t *textRenderer) Close(elapsed time.Duration) error {
+	_, err := fmt.Fprintf(t.w, "Time taken to fetch all cities: %v\n", elapsed)
+	return err
+}
+
+type jsonRenderer struct {
+	enc  *json.Encoder
+	unit TempUnit
+}
+
+func (j *jsonRenderer) Render(r WeatherResult) error {
+	return j.enc.Encode(NewRecord(r, j.unit))
+}
+
+func (j *jsonRenderer) Close(time.Duration) error { return nil }
+
+var csvHeader = []string{"city", "name", "country", "temp", "feels_like", "unit", "humidity", "pressure", "wind_speed", "conditions", "provider", "error", "attempts", "cached", "duration_ms", "rate_limit_wait_ms"}
+
+type csvRenderer struct {
+	w             *csv.Writer
+	unit          TempUnit
+	headerWritten bool
+}
+
+func (c *csvRenderer) Render(r WeatherResult) error {
+	if !c.headerWritten {
+		c.headerWritten = true
+		if err := c.w.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+
+	rec := NewRecord(r, c.unit)
+	row := []string{rec.City, rec.Name, rec.Country, formatOptional(rec.Temp), formatOptional(rec.FeelsLike), rec.Unit, "", "", "", rec.Conditions, rec.Provider, rec.Error,
+		strconv.Itoa(rec.Attempts), strconv.FormatBool(rec.Cached), strconv.FormatInt(rec.DurationMS, 10), strconv.FormatInt(rec.RateLimitMS, 10)}
+	if rec.Error == "" {
+		row[6] = strconv.Itoa(rec.Humidity)
+		row[7] = strconv.Itoa(rec.Pressure)
+		row[8] = strconv.FormatFloat(rec.WindSpeed, 'f', -1, 64)
+	}
+	return c.w.Write(row)
+}
+
+// Close writes the header if no row did, an empty result is still a valid CSV file.
+func (c *csvRenderer) Close(time.Duration) error {
+	if !c.headerWritten {
+		c.w.Write(csvHeader)
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func formatOptional(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*v, 'f', 2, 64)
+}
+
+type tableRenderer struct {
+	w             *tabwriter.Writer
+	unit          TempUnit
+	headerWritten bool
+}
+
+func (t *tableRenderer) Render(r WeatherResult) error {
+	if !t.headerWritten {
+		t.headerWritten = true
+		fmt.Fprintln(t.w, "CITY\tTEMP\tFEELS LIKE\tHUMIDITY\tWIND\tCONDITIONS\tATTEMPTS\tDURATION\tERROR")
+	}
+
+	if r.Err != nil {
+		_, err := fmt.Fprintf(t.w, "%v\t-\t-\t-\t-\t-\t%d\t%v\t%v\n", r.City, r.Attempts, r.Duration.Round(time.Millisecond), r.Err)
+		return err
+	}
+	_, err := fmt.Fprintf(t.w, "%v\t%v\t%v\t%d%%\t%.1f\t%v\t%d\t%v\t\n",
+		r.displayName(), r.Data.Main.Temp.In(t.unit).Format(1), r.Data.Main.FeelsLike.In(t.unit).Format(1), r.Data.Main.Humidity,
+		r.Data.Wind.Speed, r.Data.Description(), r.Attempts, r.Duration.Round(time.Millisecond))
+	return err
+}
+
+// Close flushes the table, tabwriter can only align the columns once it has seen every row.
+func (t *tableRenderer) Close(elapsed time.Duration) error {
+	if err := t.w.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(t.w, "Elapsed: %v\n", elapsed)
+	if err != nil {
+		return err
+	}
+	return t.w.Flush()
+}
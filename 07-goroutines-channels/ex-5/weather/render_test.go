@@ -0,0 +1,34 @@
+package weather_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"golang-fast-start/07-goroutines-channels/ex-5/weather"
+)
+
+func TestWindSpeedIsRounded(t *testing.T) {
+	r := weather.WeatherResult{City: "Oslo"}
+	r.Data.Name = "Oslo"
+	r.Data.Wind.Speed = 3.456789
+
+	if got := weather.NewRecord(r, weather.Celsius).WindSpeed; got != 3.46 {
+		t.Errorf("record wind speed %v, want 3.46", got)
+	}
+
+	var buf bytes.Buffer
+	renderer, err := weather.NewRenderer("table", &buf, weather.Celsius)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := renderer.Render(r); err != nil {
+		t.Fatal(err)
+	}
+	if err := renderer.Close(0); err != nil {
+		t.Fatal(err)
+	}
+	if out := buf.String(); !strings.Contains(out, " 3.5 ") || strings.Contains(out, "3.456") {
+		t.Errorf("the wind column isn't rounded to one decimal:\n%v", out)
+	}
+}
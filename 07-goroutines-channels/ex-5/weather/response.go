@@ -0,0 +1,151 @@
+package weather
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+)
+
+// WeatherResponse models the full current weather payload of /data/2.5/weather.
+// Every field is optional on the wire: whatever the API leaves out keeps its zero value.
+// See https://openweathermap.org/current#fields_json
+type WeatherResponse struct {
+	ID         int         `json:"id"`   // OpenWeatherMap city ID
+	Name       string      `json:"name"` // city name as resolved by the API
+	Coord      Coord       `json:"coord"`
+	Conditions []Condition `json:"weather"` // usually one entry, sometimes more (e.g. rain and mist)
+	Main       Main        `json:"main"`
+	Visibility int         `json:"visibility"` // meters, capped at 10 km
+	Wind       Wind        `json:"wind"`
+	Clouds     Clouds      `json:"clouds"`
+	Rain       Volume      `json:"rain"`
+	Snow       Volume      `json:"snow"`
+	Sys        Sys         `json:"sys"`
+	Timezone   int         `json:"timezone"` // shift from UTC in seconds
+	Time       UnixTime    `json:"dt"`       // when the data was calculated
+
+	Source string `json:"-"` // name of the Provider that answered
+}
+
+// Location returns the city's fixed UTC offset, handy for showing sunrise/sunset in local time:
+//
+//	data.Sys.Sunrise.In(data.Location())
+func (r WeatherResponse) Location() *time.Location {
+	return time.FixedZone(r.Name, r.Timezone)
+}
+
+type Coord struct {
+	Lon float64 `json:"lon"`
+	Lat float64 `json:"lat"`
+}
+
+// Condition is one entry of the "weather" array, e.g. {800 Clear "clear sky" 01d}.
+type Condition struct {
+	ID          int    `json:"id"`
+	Main        string `json:"main"`
+	Description string `json:"description"`
+	Icon        string `json:"icon"`
+}
+
+type Main struct {
+	Temp      Temperature `json:"temp"`
+	FeelsLike Temperature `json:"feels_like"`
+	TempMin   Temperature `json:"temp_min"`
+	TempMax   Temperature `json:"temp_max"`
+	Pressure  int         `json:"pressure"`   // hPa at sea level
+	Humidity  int         `json:"humidity"`   // %
+	SeaLevel  int         `json:"sea_level"`  // hPa
+	GrndLevel int         `json:"grnd_level"` // hPa
+}
+
+type Wind struct {
+	Speed float64 `json:"speed"` // m/s for Standard and Metric units, mph for Imperial
+	Deg   float64 `json:"deg"`   // direction, meteorological degrees
+	Gust  float64 `json:"gust"`
+}
+
+type Clouds struct {
+	All int `json:"all"` // cloudiness in %
+}
+
+// Volume is the precipitation of the last hour(s) in mm.
+type Volume struct {
+	OneHour    float64 `json:"1h"`
+	ThreeHours float64 `json:"3h"`
+}
+
+type Sys struct {
+	Country string   `json:"country"`
+	Sunrise UnixTime `json:"sunrise"`
+	Sunset  UnixTime `json:"sunset"`
+}
+
+// TempUnit is the scale a Temperature value is expressed in.
+type TempUnit int
+
+const (
+	Kelvin TempUnit = iota // the API default, so it is also the zero value
+	Celsius
+	Fahrenheit
+)
+
+func (u TempUnit) String() string {
+	switch u {
+	case Celsius:
+		return "°C"
+	case Fahrenheit:
+		return "°F"
+	}
+	return "K"
+}
+
+// Temperature is a value together with the unit it is expressed in.
+// The API sends plain numbers, the unit comes from how the request was made.
+type Temperature struct {
+	Value float64
+	Unit  TempUnit
+}
+
+// String uses 2 decimals, see Format for a different precision.
+func (t Temperature) String() string {
+	return t.Format(2)
+}
+
+func (t *Temperature) UnmarshalJSON(b []byte) error {
+	if bytes.Equal(b, []byte("null")) {
+		return nil
+	}
+	return json.Unmarshal(b, &t.Value)
+}
+
+// MarshalJSON writes the bare number, the same shape the API uses.
+func (t Temperature) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.Value)
+}
+
+// UnixTime decodes the Unix timestamps (seconds) the API uses into a time.Time.
+// A missing or zero timestamp stays the zero time, check it with IsZero.
+type UnixTime struct {
+	time.Time
+}
+
+func (t *UnixTime) UnmarshalJSON(b []byte) error {
+	if bytes.Equal(b, []byte("null")) {
+		return nil
+	}
+	var seconds int64
+	if err := json.Unmarshal(b, &seconds); err != nil {
+		return err
+	}
+	if seconds != 0 {
+		t.Time = time.Unix(seconds, 0).UTC()
+	}
+	return nil
+}
+
+func (t UnixTime) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte("0"), nil
+	}
+	return json.Marshal(t.Unix())
+}
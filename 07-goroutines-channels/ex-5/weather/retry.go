@@ -0,0 +1,105 @@
+package weather
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy decides how often and how patiently a failed request is repeated.
+// Only transient failures are retried (rate limiting, 5xx, dropped connections),
+// an invalid API key fails on the first attempt.
+type RetryPolicy struct {
+	MaxAttempts int           // total attempts including the first one, 1 or less disables retries
+	BaseDelay   time.Duration // wait before the first retry, doubled after every attempt
+	MaxDelay    time.Duration // upper bound for a single backoff, 0 means no bound
+}
+
+// DefaultRetryPolicy is what NewClient uses: up to 3 attempts, waiting ~0.5s then ~1s.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    5 * time.Second,
+}
+
+// backoff returns the wait before retry number n (1 for the first retry).
+// Exponential backoff with jitter: the delay doubles every time and a random half of it
+// is dropped, so goroutines that failed together don't all come back at the same moment.
+func (p RetryPolicy) backoff(n int) time.Duration {
+	delay := p.BaseDelay << (n - 1)
+	if p.MaxDelay > 0 && (delay > p.MaxDelay || delay <= 0) {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// do calls attempt until it succeeds, fails permanently, runs out of attempts or ctx is done.
+// It returns how many attempts were made together with the last error.
+func (p RetryPolicy) do(ctx context.Context, attempt func() error) (int, error) {
+	attempts := 0
+	for {
+		attempts++
+		err := attempt()
+		if err == nil || attempts >= p.MaxAttempts || !isTransient(err) {
+			return attempts, err
+		}
+		if ctx.Err() != nil {
+			return attempts, interrupted(ctx, err)
+		}
+
+		// the server knows best: never retry sooner than its Retry-After says
+		delay := p.backoff(attempts)
+		var apiErr *APIError
+		if errors.As(err, &apiErr) {
+			delay = max(delay, apiErr.RetryAfter)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return attempts, interrupted(ctx, err)
+		case <-timer.C:
+		}
+	}
+}
+
+// interrupted is the error of a retry loop stopped by ctx: ctx.Err() first, so a city cut
+// off while waiting to retry a 503 counts as timed out, with the last error kept for the message.
+func interrupted(ctx context.Context, last error) error {
+	return fmt.Errorf("%w (last attempt: %w)", ctx.Err(), last)
+}
+
+// isTransient reports whether repeating the request has a chance to succeed.
+func isTransient(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+			http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,106 @@
+package weather_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang-fast-start/07-goroutines-channels/ex-5/weather"
+	"golang-fast-start/07-goroutines-channels/ex-5/weather/fakeapi"
+)
+
+// fastRetry keeps the backoff short, the tests are about how often, not how long.
+var fastRetry = weather.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+// newFakeClient starts fake on an httptest server and returns a client pointed at it.
+func newFakeClient(t *testing.T, fake *fakeapi.Server) *weather.Client {
+	t.Helper()
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	client := weather.NewClient("test-key")
+	client.BaseURL = srv.URL
+	client.Retry = fastRetry
+	return client
+}
+
+// fetchOne goes through the fan-out, which reports the attempts.
+func fetchOne(ctx context.Context, client *weather.Client, city string) weather.WeatherResult {
+	return <-client.FetchAll(ctx, []string{city}, weather.FanOutOptions{})
+}
+
+func TestRetryTransientSucceeds(t *testing.T) {
+	fake := fakeapi.New()
+	fake.SetFault("Paris", fakeapi.Fault{Status: http.StatusServiceUnavailable, Failures: 2})
+	client := newFakeClient(t, fake)
+
+	r := fetchOne(context.Background(), client, "Paris")
+	if r.Err != nil {
+		t.Fatalf("Paris failed: %v", r.Err)
+	}
+	if r.Attempts != 3 || fake.Hits("Paris") != 3 {
+		t.Errorf("attempts %d, server hits %d, want 3 and 3", r.Attempts, fake.Hits("Paris"))
+	}
+	if !r.Retried {
+		t.Error("Retried is false after two failed attempts")
+	}
+}
+
+func TestRetryPermanentFailsFast(t *testing.T) {
+	fake := fakeapi.New()
+	fake.SetFault("London", fakeapi.Fault{Status: http.StatusUnauthorized})
+	client := newFakeClient(t, fake)
+
+	r := fetchOne(context.Background(), client, "London")
+	if !errors.Is(r.Err, weather.ErrUnauthorized) {
+		t.Fatalf("got %v, want ErrUnauthorized", r.Err)
+	}
+	if r.Attempts != 1 || fake.Hits("London") != 1 {
+		t.Errorf("a 401 was retried: attempts %d, server hits %d", r.Attempts, fake.Hits("London"))
+	}
+}
+
+func TestRetryAfterIsRespected(t *testing.T) {
+	fake := fakeapi.New()
+	fake.SetFault("Oslo", fakeapi.Fault{Status: http.StatusTooManyRequests, Failures: 1, RetryAfter: "1"})
+	client := newFakeClient(t, fake)
+
+	start := time.Now()
+	r := fetchOne(context.Background(), client, "Oslo")
+	if r.Err != nil {
+		t.Fatalf("Oslo failed: %v", r.Err)
+	}
+	// the backoff alone would retry after a few milliseconds
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %v, the server asked for 1s", elapsed)
+	}
+	if r.Attempts != 2 {
+		t.Errorf("attempts %d, want 2", r.Attempts)
+	}
+}
+
+func TestRetryCutOffByDeadline(t *testing.T) {
+	fake := fakeapi.New()
+	fake.SetFault("Paris", fakeapi.Fault{Status: http.StatusServiceUnavailable})
+	client := newFakeClient(t, fake)
+	client.Retry = weather.RetryPolicy{MaxAttempts: 5, BaseDelay: time.Second}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	r := fetchOne(ctx, client, "Paris")
+
+	// the deadline passed while waiting for the retry, that's a timeout and not a 503
+	if got := weather.ErrorCategory(r.Err); got != "timeout" {
+		t.Errorf("category %q for %v, want timeout", got, r.Err)
+	}
+	if !r.TimedOut() {
+		t.Error("TimedOut is false")
+	}
+	// the last answer is still there for the message and errors.Is
+	if !errors.Is(r.Err, weather.ErrServer) {
+		t.Errorf("the 503 was lost: %v", r.Err)
+	}
+}
@@ -0,0 +1,132 @@
+package weather
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Summary is the final accounting of a fan-out.
+type Summary struct {
+	Total     int
+	Succeeded int
+	Failed    map[string][]string // ErrorCategory -> cities, in arrival order
+	Retried   int                 // cities that needed more than one attempt of the same provider
+	FellBack  int                 // cities a Fallback had to ask another provider for
+	Cached    int
+
+	// Latency is over every city that wasn't cancelled, failed or not. A city cancelled
+	// by FailFast or the caller never finished its request, its duration says nothing.
+	Slowest         string // the city that took longest
+	SlowestDuration time.Duration
+	P50, P95        time.Duration
+	Elapsed         time.Duration // wall time of the whole fan-out
+}
+
+// Failures is the number of cities that failed, whatever the reason.
+func (s Summary) Failures() int { return s.Total - s.Succeeded }
+
+// Summarize computes the Summary of a finished fan-out.
+func Summarize(results []WeatherResult, elapsed time.Duration) Summary {
+	s := Summary{Total: len(results), Failed: map[string][]string{}, Elapsed: elapsed}
+
+	durations := make([]time.Duration, 0, len(results))
+	for _, r := range results {
+		if !errors.Is(r.Err, context.Canceled) {
+			durations = append(durations, r.Duration)
+			if r.Duration > s.SlowestDuration {
+				s.Slowest, s.SlowestDuration = r.City, r.Duration
+			}
+		}
+		if r.Retried {
+			s.Retried++
+		}
+		if r.Fallbacks > 0 {
+			s.FellBack++
+		}
+		if r.Cached {
+			s.Cached++
+		}
+
+		if r.Err != nil {
+			category := ErrorCategory(r.Err)
+			s.Failed[category] = append(s.Failed[category], r.City)
+		} else {
+			s.Succeeded++
+		}
+	}
+
+	slices.Sort(durations)
+	s.P50 = percentile(durations, 50)
+	s.P95 = percentile(durations, 95)
+	return s
+}
+
+// percentile uses the nearest-rank method on sorted durations: the smallest value that
+// at least p percent of the values are less than or equal to.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100 // ceil(p/100 * n)
+	return sorted[max(rank, 1)-1]
+}
+
+// String is the summary as printed at the end of a run:
+//
+//	8 cities in 1.204s: 6 succeeded, 2 failed
+//	  not_found (1): Nowhere
+//	  timeout (1): Oslo
+//	Latency: p50 120ms, p95 1s, slowest Oslo (1s)
+//	Retried: 1, cached: 0
+//
+// "fell back" is added to the last line when a Fallback had to use another provider.
+func (s Summary) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d cities in %v: %d succeeded, %d failed\n", s.Total, s.Elapsed.Round(time.Millisecond), s.Succeeded, s.Failures())
+	for _, category := range slices.Sorted(maps.Keys(s.Failed)) {
+		cities := s.Failed[category]
+		fmt.Fprintf(&b, "  %v (%d): %v\n", category, len(cities), strings.Join(cities, ", "))
+	}
+	if s.Slowest != "" {
+		fmt.Fprintf(&b, "Latency: p50 %v, p95 %v, slowest %v (%v)\n",
+			s.P50.Round(time.Millisecond), s.P95.Round(time.Millisecond), s.Slowest, s.SlowestDuration.Round(time.Millisecond))
+	}
+	fmt.Fprintf(&b, "Retried: %d, cached: %d", s.Retried, s.Cached)
+	if s.FellBack > 0 {
+		fmt.Fprintf(&b, ", fell back: %d", s.FellBack)
+	}
+	return b.String()
+}
+
+// FetchEach is FetchAllFrom for callers that want the accounting at the end: it hands
+// every result to fn as soon as it arrives and returns the Summary once all are in.
+//
+// When fn returns an error, fn isn't called anymore, the remaining fetches are
+// cancelled and the error is returned with the summary of what was fetched so far.
+func FetchEach(ctx context.Context, p Provider, cities []string, opts FanOutOptions, fn func(WeatherResult) error) (Summary, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	start := time.Now()
+	results := make([]WeatherResult, 0, len(cities))
+	var fnErr error
+
+	// keep receiving after an error, every goroutine of the fan-out has to be able to send
+	for r := range FetchAllFrom(ctx, p, cities, opts) {
+		if fnErr != nil {
+			continue
+		}
+		results = append(results, r)
+		if fn != nil {
+			if fnErr = fn(r); fnErr != nil {
+				cancel()
+			}
+		}
+	}
+	return Summarize(results, time.Since(start)), fnErr
+}
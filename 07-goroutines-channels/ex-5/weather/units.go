@@ -0,0 +1,97 @@
+package weather
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Units is the unit system sent as the "units" query parameter.
+// It decides the scale of every temperature and the wind speed unit in the response.
+type Units string
+
+const (
+	Standard Units = "standard" // Kelvin, m/s (what the API uses when no units are sent)
+	Metric   Units = "metric"   // Celsius, m/s
+	Imperial Units = "imperial" // Fahrenheit, mph
+)
+
+// ParseUnits accepts the three unit system names, "" means Standard.
+func ParseUnits(s string) (Units, error) {
+	switch Units(s) {
+	case "", Standard:
+		return Standard, nil
+	case Metric, Imperial:
+		return Units(s), nil
+	}
+	return "", fmt.Errorf("unknown units %q, use standard, metric or imperial", s)
+}
+
+// TempUnit returns the temperature scale the API answers with for this unit system.
+func (u Units) TempUnit() TempUnit {
+	switch u {
+	case Metric:
+		return Celsius
+	case Imperial:
+		return Fahrenheit
+	}
+	return Kelvin
+}
+
+// setTempUnit tags every temperature of the response with the scale it was requested in.
+// The API only sends numbers, so this has to happen right after decoding.
+func (r *WeatherResponse) setTempUnit(unit TempUnit) {
+	r.Main.setTempUnit(unit)
+}
+
+func (m *Main) setTempUnit(unit TempUnit) {
+	m.Temp.Unit = unit
+	m.FeelsLike.Unit = unit
+	m.TempMin.Unit = unit
+	m.TempMax.Unit = unit
+}
+
+// Kelvin returns the temperature in Kelvin, whatever unit it is stored in.
+func (t Temperature) Kelvin() float64 {
+	switch t.Unit {
+	case Celsius:
+		return t.Value + 273.15
+	case Fahrenheit:
+		return (t.Value-32)*5/9 + 273.15
+	}
+	return t.Value
+}
+
+// Celsius returns the temperature in degrees Celsius.
+func (t Temperature) Celsius() float64 {
+	return t.Kelvin() - 273.15
+}
+
+// Fahrenheit returns the temperature in degrees Fahrenheit.
+func (t Temperature) Fahrenheit() float64 {
+	return t.Celsius()*9/5 + 32
+}
+
+// In converts the temperature to another scale:
+//
+//	weather.Temperature{Value: 293.15, Unit: weather.Kelvin}.In(weather.Celsius) // 20.00°C
+func (t Temperature) In(unit TempUnit) Temperature {
+	switch unit {
+	case Celsius:
+		return Temperature{Value: t.Celsius(), Unit: Celsius}
+	case Fahrenheit:
+		return Temperature{Value: t.Fahrenheit(), Unit: Fahrenheit}
+	}
+	return Temperature{Value: t.Kelvin(), Unit: Kelvin}
+}
+
+// Format renders the temperature with the given number of decimals, e.g. Format(1) -> "21.4°C".
+func (t Temperature) Format(decimals int) string {
+	return strconv.FormatFloat(t.Value, 'f', decimals, 64) + t.Unit.String()
+}
+
+// Round2 rounds to 2 decimals. Unit conversions leave float noise like
+// 13.900000000000034 behind, which has no place in output, alerts or stored records.
+func Round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
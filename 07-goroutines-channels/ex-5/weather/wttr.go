@@ -0,0 +1,180 @@
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultWttrURL is the public wttr.in service, it needs no API key.
+const DefaultWttrURL = "https://wttr.in"
+
+// Wttr is a Provider for wttr.in, asked for its "j1" JSON format. Everything in that
+// format is a string, and the units differ from OpenWeatherMap's, so the answer is
+// converted into a WeatherResponse in Units.
+type Wttr struct {
+	BaseURL    string
+	HTTPClient *http.Client // nil uses a client with a 10 second timeout
+	Retry      RetryPolicy
+	Units      Units
+}
+
+// NewWttr returns a provider for the public service with the default retry policy.
+func NewWttr() *Wttr {
+	return &Wttr{BaseURL: DefaultWttrURL, Retry: DefaultRetryPolicy, Units: Standard}
+}
+
+func (w *Wttr) Name() string { return "wttr.in" }
+
+func (w *Wttr) Current(ctx context.Context, city string) (WeatherResponse, error) {
+	data, _, err := w.current(ctx, city)
+	return data, err
+}
+
+// wttrResponse is the part of the j1 format we use.
+type wttrResponse struct {
+	CurrentCondition []struct {
+		TempC           string      `json:"temp_C"`
+		FeelsLikeC      string      `json:"FeelsLikeC"`
+		Humidity        string      `json:"humidity"`
+		Pressure        string      `json:"pressure"`
+		WindKmph        string      `json:"windspeedKmph"`
+		WindMiles       string      `json:"windspeedMiles"`
+		WindDegree      string      `json:"winddirDegree"`
+		CloudCover      string      `json:"cloudcover"`
+		Visibility      string      `json:"visibility"` // km
+		PrecipMM        string      `json:"precipMM"`
+		Description     []wttrValue `json:"weatherDesc"`
+		ObservationTime string      `json:"observation_time"` // "06:53 AM", UTC
+	} `json:"current_condition"`
+	NearestArea []struct {
+		AreaName  []wttrValue `json:"areaName"`
+		Country   []wttrValue `json:"country"`
+		Latitude  string      `json:"latitude"`
+		Longitude string      `json:"longitude"`
+	} `json:"nearest_area"`
+}
+
+type wttrValue struct {
+	Value string `json:"value"`
+}
+
+func (w *Wttr) current(ctx context.Context, city string) (WeatherResponse, fetchInfo, error) {
+	q, err := ParseQuery(city)
+	if err != nil {
+		return WeatherResponse{}, fetchInfo{}, err
+	}
+	location := q.Name
+	switch {
+	case q.ID != 0:
+		return WeatherResponse{}, fetchInfo{}, fmt.Errorf("wttr.in has no city IDs, use a name or coordinates instead of %v", city)
+	case q.Coord != nil:
+		location = fmt.Sprintf("%v,%v", q.Coord.Lat, q.Coord.Lon)
+	}
+
+	endpoint := strings.TrimSuffix(w.BaseURL, "/") + "/" + url.PathEscape(location) + "?format=j1"
+
+	var raw wttrResponse
+	attempts, err := w.Retry.do(ctx, func() error {
+		return w.get(ctx, endpoint, &raw)
+	})
+	info := fetchInfo{attempts: attempts}
+	if err != nil {
+		return WeatherResponse{}, info, err
+	}
+	if len(raw.CurrentCondition) == 0 {
+		return WeatherResponse{}, info, errors.New("wttr.in: no current conditions in the answer")
+	}
+
+	data := w.convert(raw)
+	data.Source = w.Name()
+	return data, info, nil
+}
+
+func (w *Wttr) get(ctx context.Context, endpoint string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	client := w.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		// same error type as OpenWeatherMap's, so ErrCityNotFound and retries work alike
+		return newAPIError(resp, body)
+	}
+	return json.Unmarshal(body, v)
+}
+
+// convert maps the j1 strings onto the OpenWeatherMap shape, in w.Units.
+func (w *Wttr) convert(raw wttrResponse) WeatherResponse {
+	cur := raw.CurrentCondition[0]
+	unit := w.Units.TempUnit()
+
+	var data WeatherResponse
+	data.Main.Temp = Temperature{Value: number(cur.TempC), Unit: Celsius}.In(unit)
+	data.Main.FeelsLike = Temperature{Value: number(cur.FeelsLikeC), Unit: Celsius}.In(unit)
+	data.Main.TempMin, data.Main.TempMax = data.Main.Temp, data.Main.Temp
+	data.Main.Humidity = int(number(cur.Humidity))
+	data.Main.Pressure = int(number(cur.Pressure))
+
+	// OpenWeatherMap answers in mph for imperial units and m/s otherwise
+	if w.Units == Imperial {
+		data.Wind.Speed = number(cur.WindMiles)
+	} else {
+		data.Wind.Speed = Round2(number(cur.WindKmph) / 3.6)
+	}
+	data.Wind.Deg = number(cur.WindDegree)
+	data.Clouds.All = int(number(cur.CloudCover))
+	data.Visibility = int(number(cur.Visibility) * 1000)
+	data.Rain.OneHour = number(cur.PrecipMM)
+
+	if len(cur.Description) > 0 {
+		desc := strings.TrimSpace(cur.Description[0].Value)
+		data.Conditions = []Condition{{Main: desc, Description: strings.ToLower(desc)}}
+	}
+	if t, err := time.Parse("03:04 PM", cur.ObservationTime); err == nil {
+		now := time.Now().UTC()
+		observed := time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, time.UTC)
+		if observed.After(now.Add(time.Hour)) {
+			observed = observed.AddDate(0, 0, -1) // observed before midnight UTC
+		}
+		data.Time = UnixTime{observed}
+	}
+
+	if len(raw.NearestArea) > 0 {
+		area := raw.NearestArea[0]
+		if len(area.AreaName) > 0 {
+			data.Name = area.AreaName[0].Value
+		}
+		if len(area.Country) > 0 {
+			data.Sys.Country = area.Country[0].Value // a full name, not a code like OpenWeatherMap's
+		}
+		data.Coord = Coord{Lat: number(area.Latitude), Lon: number(area.Longitude)}
+	}
+	return data
+}
+
+// number parses the numbers j1 sends as strings, a missing one is 0.
+func number(s string) float64 {
+	f, _ := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	return f
+}
@@ -0,0 +1,105 @@
+package weather_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang-fast-start/07-goroutines-channels/ex-5/weather"
+	"golang-fast-start/07-goroutines-channels/ex-5/weather/fakeapi"
+)
+
+// newWttr points a wttr.in provider at the j1 endpoint of fake.
+func newWttr(t *testing.T, fake *fakeapi.Server, units weather.Units) *weather.Wttr {
+	t.Helper()
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	w := weather.NewWttr()
+	w.BaseURL = srv.URL
+	w.Retry = fastRetry
+	w.Units = units
+	return w
+}
+
+// station returns Oslo at 20°C and 10 m/s of wind, measured at the given time.
+func station(name string, measured time.Time) weather.WeatherResponse {
+	var data weather.WeatherResponse
+	for _, city := range fakeapi.DefaultCities() {
+		if city.Name == "Oslo" {
+			data = city
+		}
+	}
+	data.Name = name
+	data.Main.Temp = weather.Temperature{Value: 293.15, Unit: weather.Kelvin}
+	data.Main.FeelsLike = weather.Temperature{Value: 291.15, Unit: weather.Kelvin}
+	data.Wind.Speed = 10
+	data.Time = weather.UnixTime{Time: measured}
+	return data
+}
+
+func TestWttrConversion(t *testing.T) {
+	measured := time.Now().UTC().Add(-30 * time.Minute).Truncate(time.Minute)
+	fake := fakeapi.New()
+	fake.SetCity(station("Oslo", measured))
+
+	tests := []struct {
+		units     weather.Units
+		temp      float64
+		feelsLike float64
+		wind      float64 // m/s, or mph for imperial like OpenWeatherMap
+	}{
+		{weather.Metric, 20, 18, 10},     // 36 km/h
+		{weather.Imperial, 68, 64.4, 22}, // j1 has whole mph
+		{weather.Standard, 293.15, 291.15, 10},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.units), func(t *testing.T) {
+			data, err := newWttr(t, fake, tt.units).Current(context.Background(), "Oslo")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := weather.Round2(data.Main.Temp.Value); got != tt.temp || data.Main.Temp.Unit != tt.units.TempUnit() {
+				t.Errorf("temp %v %v, want %v %v", got, data.Main.Temp.Unit, tt.temp, tt.units.TempUnit())
+			}
+			if got := weather.Round2(data.Main.FeelsLike.Value); got != tt.feelsLike {
+				t.Errorf("feels like %v, want %v", got, tt.feelsLike)
+			}
+			if data.Wind.Speed != tt.wind {
+				t.Errorf("wind %v, want %v", data.Wind.Speed, tt.wind)
+			}
+			if data.Name != "Oslo" || data.Sys.Country != "Norway" || data.Source != "wttr.in" {
+				t.Errorf("name %q, country %q, source %q", data.Name, data.Sys.Country, data.Source)
+			}
+			if !data.Time.Equal(measured) {
+				t.Errorf("measured at %v, want %v", data.Time, measured)
+			}
+		})
+	}
+}
+
+func TestWttrObservationTimeRollover(t *testing.T) {
+	// j1 only sends the time of day: one that is later than now was taken yesterday
+	now := time.Now().UTC().Truncate(time.Minute)
+	tests := []struct {
+		name     string
+		measured time.Time
+	}{
+		{"earlier today", now.Add(-30 * time.Minute)},
+		{"yesterday evening", now.Add(2*time.Hour - 24*time.Hour)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := fakeapi.New()
+			fake.SetCity(station("Oslo", tt.measured))
+			data, err := newWttr(t, fake, weather.Metric).Current(context.Background(), "Oslo")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !data.Time.Equal(tt.measured) {
+				t.Errorf("measured at %v, want %v", data.Time.UTC(), tt.measured)
+			}
+		})
+	}
+}